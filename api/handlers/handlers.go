@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"encoding/base64"
+	"errors"
 	"net/http"
 	"strings"
 
@@ -11,15 +13,61 @@ import (
 )
 
 type ConvertRequest struct {
-	OpenAPISpec string `json:"openapi_spec" binding:"required"`
+	OpenAPISpec string `json:"openapi_spec"`
+	// OpenAPIBundle 是 base64 编码的 zip/tar/tar.gz 规范包，用于包含相对路径 $ref 的多文件规范
+	OpenAPIBundle string `json:"openapi_bundle"`
+	// BundleEntry 是规范包中入口文件的路径，为空时自动查找 openapi.* 或 swagger.*
+	BundleEntry string `json:"bundle_entry"`
 	Options     struct {
-		ServerName       string                 `json:"server_name"`
-		ToolNamePrefix   string                 `json:"tool_name_prefix"`
-		ServerConfig     map[string]interface{} `json:"server_config"`
-		ResponseTemplate string                 `json:"response_template"`
-		Validate         bool                   `json:"validate"`
+		ServerName             string                 `json:"server_name"`
+		ToolNamePrefix         string                 `json:"tool_name_prefix"`
+		ToolNameCasing         string                 `json:"tool_name_casing" binding:"omitempty,oneof=snake camel"`
+		ToolNameMaxLength      int                    `json:"tool_name_max_length" binding:"omitempty,min=10"`
+		ServerVariables        map[string]string      `json:"server_variables"`
+		ServerIndex            int                    `json:"server_index" binding:"min=0"`
+		ServerDescription      string                 `json:"server_description"`
+		BaseURL                string                 `json:"base_url"`
+		ServerConfig           map[string]interface{} `json:"server_config"`
+		ResponseTemplate       string                 `json:"response_template"`
+		DescribeErrorResponses bool                   `json:"describe_error_responses"`
+		ResponseTextTemplate   string                 `json:"response_text_template"`
+		Template               string                 `json:"template"`
+		ContentTypePreference  []string               `json:"content_type_preference"`
+		BodyArgName            string                 `json:"body_arg_name"`
+		ArgNameCollision       string                 `json:"arg_name_collision" binding:"omitempty,oneof=prefix nest"`
+		ArgNamePrefixes        map[string]string      `json:"arg_name_prefixes"`
+		BodyMode               string                 `json:"body_mode" binding:"omitempty,oneof=flatten nested auto"`
+		BodyMaxDepth           int                    `json:"body_max_depth" binding:"min=0"`
+		BodyMaxProperties      int                    `json:"body_max_properties" binding:"min=0"`
+		Validate               bool                   `json:"validate"`
+		// Filter 选择转换为工具的操作，见 models.OperationFilter
+		Filter struct {
+			IncludeTags        []string `json:"include_tags"`
+			ExcludeTags        []string `json:"exclude_tags"`
+			IncludePaths       []string `json:"include_paths"`
+			ExcludePaths       []string `json:"exclude_paths"`
+			IncludeMethods     []string `json:"include_methods"`
+			ExcludeMethods     []string `json:"exclude_methods"`
+			IncludeOperationID string   `json:"include_operation_id"`
+			ExcludeOperationID string   `json:"exclude_operation_id"`
+			ExcludeDeprecated  bool     `json:"exclude_deprecated"`
+			AllowTools         bool     `json:"allow_tools"`
+		} `json:"filter"`
 	} `json:"options"`
-	Format string `json:"format" binding:"required,oneof=yaml json"`
+	// Format 为 yaml 或 json 时返回 Higress MCP 服务器配置，为 mcp 时以 JSON 返回 MCP 标准的工具定义
+	Format string `json:"format" binding:"required,oneof=yaml json mcp"`
+}
+
+// ConfigResponse 是 format 为 yaml 或 json 时的响应，在 MCP 服务器配置之外附带转换警告
+type ConfigResponse struct {
+	models.MCPConfig `yaml:",inline"`
+	Warnings         []string `json:"warnings,omitempty" yaml:"warnings,omitempty"`
+}
+
+// ToolListResponse 是 format 为 mcp 时的响应，在工具定义之外附带转换警告
+type ToolListResponse struct {
+	models.MCPToolList
+	Warnings []string `json:"warnings,omitempty"`
 }
 
 // HealthCheck 处理健康检查请求
@@ -32,11 +80,8 @@ func ConvertOpenAPI(c *gin.Context) {
 	var req ConvertRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		// 处理绑定错误，提供更友好的错误提示
-		if strings.Contains(err.Error(), "Key: 'ConvertRequest.OpenAPISpec'") &&
-			strings.Contains(err.Error(), "Error:Field validation for 'OpenAPISpec' failed on the 'required' tag") {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "必须提供 openapi_spec 参数，且不能为空"})
-		} else if strings.Contains(err.Error(), "Key: 'ConvertRequest.Format'") {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "format 参数必须为 'yaml' 或 'json'"})
+		if strings.Contains(err.Error(), "Key: 'ConvertRequest.Format'") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "format 参数必须为 'yaml'、'json' 或 'mcp'"})
 		} else {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "请求格式错误: " + err.Error()})
 		}
@@ -44,8 +89,8 @@ func ConvertOpenAPI(c *gin.Context) {
 	}
 
 	// 专门校验 openapi_spec 是否为空
-	if req.OpenAPISpec == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "必须提供 openapi_spec 参数，且不能为空"})
+	if req.OpenAPISpec == "" && req.OpenAPIBundle == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "必须提供 openapi_spec 或 openapi_bundle 参数，且不能为空"})
 		return
 	}
 
@@ -53,11 +98,24 @@ func ConvertOpenAPI(c *gin.Context) {
 	p := parser.NewParser()
 	p.SetValidation(req.Options.Validate)
 
-	// 解析 OpenAPI 规范
-	if err := p.ParseContent([]byte(req.OpenAPISpec)); err != nil {
+	// 解析 OpenAPI 规范，优先使用规范包
+	var err error
+	if req.OpenAPIBundle != "" {
+		bundle, decodeErr := base64.StdEncoding.DecodeString(req.OpenAPIBundle)
+		if decodeErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "openapi_bundle 必须是 base64 编码的 zip 或 tar 文件: " + decodeErr.Error()})
+			return
+		}
+		err = p.ParseBundle(bundle, req.BundleEntry)
+	} else {
+		err = p.ParseContent([]byte(req.OpenAPISpec))
+	}
+	if err != nil {
 		errMsg := "解析 OpenAPI 规范失败"
 		if strings.Contains(err.Error(), "unmarshal") {
 			errMsg = "OpenAPI规范格式错误，请确保提供的是有效的YAML或JSON格式"
+		} else if strings.Contains(err.Error(), "resolve references") {
+			errMsg = "OpenAPI规范中的 $ref 引用无法解析，请检查引用路径是否存在或是否存在循环引用"
 		} else if strings.Contains(err.Error(), "validation") {
 			errMsg = "OpenAPI规范验证失败，请确保符合 OpenAPI-3.0 标准"
 		}
@@ -67,24 +125,59 @@ func ConvertOpenAPI(c *gin.Context) {
 
 	// 创建转换器
 	convertOptions := models.ConvertOptions{
-		ServerName:       req.Options.ServerName,
-		ToolNamePrefix:   req.Options.ToolNamePrefix,
-		ServerConfig:     req.Options.ServerConfig,
-		ResponseTemplate: req.Options.ResponseTemplate,
+		ServerName:             req.Options.ServerName,
+		ToolNamePrefix:         req.Options.ToolNamePrefix,
+		ToolNameCasing:         req.Options.ToolNameCasing,
+		ToolNameMaxLength:      req.Options.ToolNameMaxLength,
+		ServerVariables:        req.Options.ServerVariables,
+		ServerIndex:            req.Options.ServerIndex,
+		ServerDescription:      req.Options.ServerDescription,
+		BaseURL:                req.Options.BaseURL,
+		ServerConfig:           req.Options.ServerConfig,
+		ResponseTemplate:       req.Options.ResponseTemplate,
+		DescribeErrorResponses: req.Options.DescribeErrorResponses,
+		ResponseTextTemplate:   req.Options.ResponseTextTemplate,
+		Template:               req.Options.Template,
+		ContentTypePreference:  req.Options.ContentTypePreference,
+		BodyArgName:            req.Options.BodyArgName,
+		ArgNameCollision:       req.Options.ArgNameCollision,
+		ArgNamePrefixes:        req.Options.ArgNamePrefixes,
+		BodyMode:               req.Options.BodyMode,
+		BodyMaxDepth:           req.Options.BodyMaxDepth,
+		BodyMaxProperties:      req.Options.BodyMaxProperties,
+		Filter:                 models.OperationFilter(req.Options.Filter),
+	}
+	if req.Format == "mcp" {
+		convertOptions.OutputMode = models.OutputModeMCP
 	}
 	conv := converter.NewConverter(p, convertOptions)
 
 	// 执行转换
-	config, err := conv.Convert()
+	result, err := conv.ConvertOutput()
 	if err != nil {
+		// 无效的转换选项（如无法编译的过滤正则、未知的请求体模式）属于客户端错误
+		if errors.Is(err, converter.ErrInvalidOptions) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "转换选项无效: " + err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "转换失败: " + err.Error()})
 		return
 	}
 
+	// 转换警告（如未选用的请求体媒体类型）放在响应体的 warnings 字段中返回，
+	// 其中可能包含规范提供的任意长度的字符串，不适合放入响应头
+	var response interface{} = result
+	switch r := result.(type) {
+	case *models.MCPConfig:
+		response = ConfigResponse{MCPConfig: *r, Warnings: conv.Warnings()}
+	case *models.MCPToolList:
+		response = ToolListResponse{MCPToolList: *r, Warnings: conv.Warnings()}
+	}
+
 	// 根据请求的格式返回结果
-	if req.Format == "json" {
-		c.JSON(http.StatusOK, config)
+	if req.Format == "yaml" {
+		c.YAML(http.StatusOK, response)
 	} else {
-		c.YAML(http.StatusOK, config)
+		c.JSON(http.StatusOK, response)
 	}
 }
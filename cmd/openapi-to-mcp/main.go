@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/higress-group/openapi-to-mcpserver/internal/converter"
+	"github.com/higress-group/openapi-to-mcpserver/internal/mcpserver"
+	"github.com/higress-group/openapi-to-mcpserver/internal/models"
+	"github.com/higress-group/openapi-to-mcpserver/internal/parser"
+	"gopkg.in/yaml.v3"
+)
+
+// Exit codes reported to the calling shell or CI job
+const (
+	exitOK         = 0
+	exitError      = 1 // I/O errors, e.g. the template or output file cannot be accessed
+	exitUsage      = 2
+	exitParse      = 3
+	exitValidation = 4
+	exitConversion = 5
+)
+
+// bundleExtensions lists the input file extensions read as spec bundles
+var bundleExtensions = []string{".zip", ".tar", ".tar.gz", ".tgz"}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command and returns its exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("openapi-to-mcp", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	input := flags.String("input", "", "OpenAPI specification file (YAML or JSON), or a zip/tar/tar.gz bundle of a multi-file specification")
+	output := flags.String("output", "", "output file (default: standard output)")
+	serverName := flags.String("server-name", "", "MCP server name (default: openapi-server)")
+	toolPrefix := flags.String("tool-prefix", "", "prefix added to every tool name")
+	templatePath := flags.String("template", "", "YAML template merged into the generated configuration")
+	responseTextTemplatePath := flags.String("response-text-template", "", "Go text/template file rendering the response description of every tool")
+	validate := flags.Bool("validate", false, "validate the OpenAPI specification")
+	format := flags.String("format", "yaml", "output format: yaml or json for the Higress configuration, mcp for MCP tool definitions in JSON")
+	contentTypes := flags.String("content-type-preference", "", "comma separated order in which request media types are chosen, e.g. application/json,application/*+json")
+	serve := flags.String("serve", "", "serve the generated tools over MCP instead of writing the configuration: stdio or http")
+	listen := flags.String("listen", "127.0.0.1:8081", "listen address of --serve http, which serves MCP at /mcp")
+	allowOrigins := flags.String("allow-origin", "", "comma separated browser origins accepted by --serve http besides localhost, e.g. https://app.example.com")
+	fileURLHosts := flags.String("file-url-hosts", "", "comma separated hosts, e.g. files.example.com or *.example.com, file arguments of --serve may be downloaded from (default: none, only base64 content)")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: openapi-to-mcp --input <spec> [options]")
+		fmt.Fprintln(stderr)
+		fmt.Fprintln(stderr, "Converts an OpenAPI specification into an MCP server configuration.")
+		fmt.Fprintln(stderr)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if *input == "" {
+		fmt.Fprintln(stderr, "error: --input is required")
+		flags.Usage()
+		return exitUsage
+	}
+	if *format != "yaml" && *format != "json" && *format != "mcp" {
+		fmt.Fprintf(stderr, "error: --format must be yaml, json or mcp, got %q\n", *format)
+		return exitUsage
+	}
+	if *serve != "" && *serve != "stdio" && *serve != "http" {
+		fmt.Fprintf(stderr, "error: --serve must be stdio or http, got %q\n", *serve)
+		return exitUsage
+	}
+
+	options := models.ConvertOptions{
+		ServerName:     *serverName,
+		ToolNamePrefix: *toolPrefix,
+	}
+	if *contentTypes != "" {
+		options.ContentTypePreference = strings.Split(*contentTypes, ",")
+	}
+	if *templatePath != "" {
+		template, err := os.ReadFile(*templatePath)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: failed to read template: %v\n", err)
+			return exitError
+		}
+		options.Template = string(template)
+	}
+	if *responseTextTemplatePath != "" {
+		responseTextTemplate, err := os.ReadFile(*responseTextTemplatePath)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: failed to read response text template: %v\n", err)
+			return exitError
+		}
+		options.ResponseTextTemplate = string(responseTextTemplate)
+	}
+
+	// Parse the specification
+	p := parser.NewParser()
+	p.SetValidation(*validate)
+	if err := parseInput(p, *input); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		if errors.Is(err, parser.ErrValidation) {
+			return exitValidation
+		}
+		return exitParse
+	}
+
+	// Convert it to an MCP configuration, or to MCP tool definitions. The
+	// runtime always serves the configuration.
+	if *format == "mcp" && *serve == "" {
+		options.OutputMode = models.OutputModeMCP
+	}
+	conv := converter.NewConverter(p, options)
+	result, err := conv.ConvertOutput()
+	if err != nil {
+		fmt.Fprintf(stderr, "error: conversion failed: %v\n", err)
+		if errors.Is(err, converter.ErrInvalidOptions) {
+			return exitUsage
+		}
+		return exitConversion
+	}
+	for _, warning := range conv.Warnings() {
+		fmt.Fprintf(stderr, "warning: %s\n", warning)
+	}
+
+	if *serve != "" {
+		options := serveOptions{
+			transport:    *serve,
+			listen:       *listen,
+			origins:      splitList(*allowOrigins),
+			fileURLHosts: splitList(*fileURLHosts),
+		}
+		if err := serveConfig(result.(*models.MCPConfig), options, stderr); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitError
+		}
+		return exitOK
+	}
+
+	data, err := encode(result, *format)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: failed to encode configuration: %v\n", err)
+		return exitError
+	}
+	if *output == "" {
+		if _, err := stdout.Write(data); err != nil {
+			fmt.Fprintf(stderr, "error: failed to write configuration: %v\n", err)
+			return exitError
+		}
+		return exitOK
+	}
+	if err := os.WriteFile(*output, data, 0644); err != nil {
+		fmt.Fprintf(stderr, "error: failed to write configuration: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// serveOptions configures the built-in MCP server of --serve
+type serveOptions struct {
+	// transport is stdio or http
+	transport string
+	listen    string
+	// origins are the browser origins accepted over HTTP besides localhost
+	origins []string
+	// fileURLHosts are the hosts file arguments may be downloaded from
+	fileURLHosts []string
+}
+
+// serveConfig serves the tools of config over stdio or Streamable HTTP until
+// the input ends or the process is interrupted
+func serveConfig(config *models.MCPConfig, options serveOptions, stderr io.Writer) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	server := mcpserver.NewServer(config, nil)
+	server.SetAllowedOrigins(options.origins)
+	server.SetFileURLHosts(options.fileURLHosts)
+	if options.transport == "stdio" {
+		return server.ServeStdio(ctx, os.Stdin, os.Stdout)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/mcp", server)
+	httpServer := &http.Server{Addr: options.listen, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		httpServer.Shutdown(context.Background())
+	}()
+
+	fmt.Fprintf(stderr, "serving %d tools on %s at /mcp\n", len(config.Tools), options.listen)
+	if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// splitList splits a comma separated flag value, which may be empty
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// parseInput parses a specification file or bundle
+func parseInput(p *parser.Parser, input string) error {
+	name := strings.ToLower(filepath.Base(input))
+	for _, ext := range bundleExtensions {
+		if strings.HasSuffix(name, ext) {
+			archive, err := os.ReadFile(input)
+			if err != nil {
+				return fmt.Errorf("failed to read file: %w", err)
+			}
+			return p.ParseBundle(archive, "")
+		}
+	}
+	return p.ParseFile(input)
+}
+
+// encode serializes the conversion result in the requested format. The JSON
+// form matches the output of the HTTP API.
+func encode(config interface{}, format string) ([]byte, error) {
+	if format != "yaml" {
+		data, err := json.MarshalIndent(config, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	}
+
+	var b strings.Builder
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(config); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,61 @@
+package converter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/higress-group/openapi-to-mcpserver/internal/models"
+)
+
+// annotationsExtension is the vendor extension overriding derived annotations
+const annotationsExtension = "x-mcp-annotations"
+
+// createAnnotations derives the MCP annotations of an operation from its HTTP
+// method: GET, HEAD, OPTIONS and TRACE only read, POST only adds, PUT, PATCH
+// and DELETE may modify or remove existing data, and PUT and DELETE are
+// idempotent. Every tool calls an external API, so it is open world. Values
+// from the x-mcp-annotations extension take precedence.
+func createAnnotations(method string, operation *openapi3.Operation) (*models.ToolAnnotations, error) {
+	annotations := &models.ToolAnnotations{
+		Title:         operation.Summary,
+		OpenWorldHint: boolPtr(true),
+	}
+
+	switch strings.ToUpper(method) {
+	case "GET", "HEAD", "OPTIONS", "TRACE":
+		annotations.ReadOnlyHint = boolPtr(true)
+	case "POST":
+		annotations.ReadOnlyHint = boolPtr(false)
+		annotations.DestructiveHint = boolPtr(false)
+		annotations.IdempotentHint = boolPtr(false)
+	case "PATCH":
+		annotations.ReadOnlyHint = boolPtr(false)
+		annotations.DestructiveHint = boolPtr(true)
+		annotations.IdempotentHint = boolPtr(false)
+	case "PUT", "DELETE":
+		annotations.ReadOnlyHint = boolPtr(false)
+		annotations.DestructiveHint = boolPtr(true)
+		annotations.IdempotentHint = boolPtr(true)
+	}
+
+	override, ok := operation.Extensions[annotationsExtension]
+	if !ok {
+		return annotations, nil
+	}
+	data, err := json.Marshal(override)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", annotationsExtension, err)
+	}
+	// Unmarshalling into the derived annotations only replaces the given fields
+	if err := json.Unmarshal(data, annotations); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", annotationsExtension, err)
+	}
+	return annotations, nil
+}
+
+// boolPtr returns a pointer to b
+func boolPtr(b bool) *bool {
+	return &b
+}
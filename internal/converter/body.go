@@ -0,0 +1,98 @@
+package converter
+
+import (
+	"fmt"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/higress-group/openapi-to-mcpserver/internal/models"
+)
+
+// Thresholds of the auto body mode above which a body is nested
+const (
+	defaultBodyMaxDepth      = 2
+	defaultBodyMaxProperties = 20
+)
+
+// validateBodyOptions checks the options controlling how request bodies and
+// colliding argument names are turned into arguments
+func (c *Converter) validateBodyOptions() error {
+	switch c.options.BodyMode {
+	case "", models.BodyModeFlatten, models.BodyModeNested, models.BodyModeAuto:
+	default:
+		return fmt.Errorf("unsupported body mode %q, expected %q, %q or %q", c.options.BodyMode, models.BodyModeFlatten, models.BodyModeNested, models.BodyModeAuto)
+	}
+	switch c.options.ArgNameCollision {
+	case "", models.ArgNameCollisionPrefix, models.ArgNameCollisionNest:
+	default:
+		return fmt.Errorf("unsupported argument name collision handling %q, expected %q or %q", c.options.ArgNameCollision, models.ArgNameCollisionPrefix, models.ArgNameCollisionNest)
+	}
+	return nil
+}
+
+// nestedBody reports whether a JSON object body is passed as a single
+// argument carrying its full schema rather than one argument per top-level
+// property: always in the nested body mode, for large, deep or free-form
+// bodies in the auto mode, and when a property collides with a parameter
+// name and such collisions are resolved by nesting
+func (c *Converter) nestedBody(contentType string, mediaType *openapi3.MediaType, params, bodyArgs []models.Arg) bool {
+	if !isJSONMediaType(contentType) || c.isRawBody(contentType, mediaType) || mediaType == nil || mediaType.Schema == nil || mediaType.Schema.Value == nil {
+		return false
+	}
+
+	switch c.options.BodyMode {
+	case models.BodyModeNested:
+		return true
+	case models.BodyModeAuto:
+		// A free-form object has no properties to flatten
+		depth, size := c.schemaSize(c.flattenSchema(mediaType.Schema.Value), 0)
+		if len(bodyArgs) == 0 || depth > c.bodyMaxDepth() || size > c.bodyMaxProperties() {
+			return true
+		}
+	}
+	return c.nestArgs() && argNamesCollide(params, bodyArgs)
+}
+
+// bodyMaxDepth returns the deepest object nesting still flattened in the
+// auto body mode
+func (c *Converter) bodyMaxDepth() int {
+	if c.options.BodyMaxDepth > 0 {
+		return c.options.BodyMaxDepth
+	}
+	return defaultBodyMaxDepth
+}
+
+// bodyMaxProperties returns the largest number of properties still
+// flattened in the auto body mode
+func (c *Converter) bodyMaxProperties() int {
+	if c.options.BodyMaxProperties > 0 {
+		return c.options.BodyMaxProperties
+	}
+	return defaultBodyMaxProperties
+}
+
+// schemaSize measures an object schema: the depth of its deepest property,
+// counting the top-level properties as 1, and its number of properties
+// including nested ones. Array items count as their own properties.
+func (c *Converter) schemaSize(schema *openapi3.Schema, depth int, ancestors ...*openapi3.Schema) (int, int) {
+	if depth > maxPropertyRecursionDepth || isRecursive(schema, ancestors) {
+		return depth, 0
+	}
+	ancestors = append(ancestors, schema)
+
+	maxDepth, size := depth, 0
+	for _, propRef := range schema.Properties {
+		if propRef == nil || propRef.Value == nil {
+			continue
+		}
+		propSchema := c.flattenSchema(propRef.Value)
+		if propSchema.Type == "array" && propSchema.Items != nil && propSchema.Items.Value != nil {
+			propSchema = c.flattenSchema(propSchema.Items.Value)
+		}
+		propDepth, propSize := c.schemaSize(propSchema, depth+1, ancestors...)
+		if propDepth > maxDepth {
+			maxDepth = propDepth
+		}
+		size += 1 + propSize
+	}
+	return maxDepth, size
+}
@@ -0,0 +1,83 @@
+package converter
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/higress-group/openapi-to-mcpserver/internal/models"
+)
+
+// argPositions ranks argument positions: when arguments from different
+// positions share a name, the one with the earliest position keeps it
+var argPositions = []string{"path", "query", "header", "cookie", "body"}
+
+// argPositionRank returns the rank of a position in argPositions
+func argPositionRank(position string) int {
+	for i, p := range argPositions {
+		if p == position {
+			return i
+		}
+	}
+	return len(argPositions)
+}
+
+// argNamePrefix returns the prefix added to the name of a colliding
+// argument, e.g. body_ for body properties
+func (c *Converter) argNamePrefix(position string) string {
+	if prefix, ok := c.options.ArgNamePrefixes[position]; ok && prefix != "" {
+		return prefix
+	}
+	return position + "_"
+}
+
+// nestArgs reports whether body arguments colliding with parameters are
+// nested under a single body argument rather than prefixed
+func (c *Converter) nestArgs() bool {
+	return c.options.ArgNameCollision == models.ArgNameCollisionNest
+}
+
+// argNamesCollide reports whether a body argument has the name of a parameter
+func argNamesCollide(params, bodyArgs []models.Arg) bool {
+	names := make(map[string]bool, len(params))
+	for _, arg := range params {
+		names[arg.Name] = true
+	}
+	for _, arg := range bodyArgs {
+		if names[arg.Name] {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveArgNames renames the arguments whose name is already taken by an
+// argument of an earlier position, prefixing them with their position. The
+// renamed arguments remember their original name, which the runtime uses in
+// the request. An argument carrying the whole body has no name in the
+// request, so wholeBody only renames it.
+func (c *Converter) resolveArgNames(toolName string, args []models.Arg, wholeBody bool) []models.Arg {
+	sort.SliceStable(args, func(i, j int) bool {
+		return argPositionRank(args[i].Position) < argPositionRank(args[j].Position)
+	})
+
+	used := make(map[string]bool, len(args))
+	for i := range args {
+		arg := &args[i]
+		if !used[arg.Name] {
+			used[arg.Name] = true
+			continue
+		}
+
+		name := c.argNamePrefix(arg.Position) + arg.Name
+		for n := 2; used[name]; n++ {
+			name = fmt.Sprintf("%s%s_%d", c.argNamePrefix(arg.Position), arg.Name, n)
+		}
+		c.warnings = append(c.warnings, fmt.Sprintf("%s: renamed %s argument %s to %s to avoid a name collision", toolName, arg.Position, arg.Name, name))
+		if arg.Position != "body" || !wholeBody {
+			arg.OriginalName = arg.Name
+		}
+		arg.Name = name
+		used[name] = true
+	}
+	return args
+}
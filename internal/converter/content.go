@@ -0,0 +1,203 @@
+package converter
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/higress-group/openapi-to-mcpserver/internal/models"
+)
+
+// defaultContentTypePreference is the order in which request media types are
+// chosen when an operation accepts several of them
+var defaultContentTypePreference = []string{
+	"application/json",
+	"application/*+json",
+	"application/x-www-form-urlencoded",
+	"multipart/form-data",
+	"application/octet-stream",
+	"text/plain",
+	"*/*",
+}
+
+// selectRequestContent chooses the media type of a request body following
+// the content type preference. Each preference is a media type or a pattern
+// such as application/*+json; media types matching none of them are chosen
+// in alphabetical order. The media types not chosen are returned as dropped.
+func (c *Converter) selectRequestContent(requestBody *openapi3.RequestBody) (string, *openapi3.MediaType, []string) {
+	if requestBody == nil || len(requestBody.Content) == 0 {
+		return "", nil, nil
+	}
+
+	preference := c.options.ContentTypePreference
+	if len(preference) == 0 {
+		preference = defaultContentTypePreference
+	}
+	selected, dropped := selectContent(requestBody.Content, preference)
+	return selected, requestBody.Content[selected], dropped
+}
+
+// selectContent chooses one of the media types of content: the first one
+// matching the earliest preference, or the alphabetically first one when
+// none matches. The media types not chosen are returned as dropped.
+func selectContent(content openapi3.Content, preference []string) (string, []string) {
+	contentTypes := make([]string, 0, len(content))
+	for contentType := range content {
+		contentTypes = append(contentTypes, contentType)
+	}
+	sort.Strings(contentTypes)
+
+	selected := contentTypes[0]
+selection:
+	for _, pattern := range preference {
+		for _, contentType := range contentTypes {
+			if mediaTypeMatches(pattern, contentType) {
+				selected = contentType
+				break selection
+			}
+		}
+	}
+
+	var dropped []string
+	for _, contentType := range contentTypes {
+		if contentType != selected {
+			dropped = append(dropped, contentType)
+		}
+	}
+	return selected, dropped
+}
+
+// mediaTypeMatches reports whether a media type, ignoring its parameters,
+// matches a preference pattern. Patterns may use * wildcards, e.g. text/*.
+func mediaTypeMatches(pattern, contentType string) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	matched, err := path.Match(pattern, mediaType)
+	return err == nil && matched
+}
+
+// fileDescription explains to the caller how to pass a file
+const fileDescription = "File content, base64 encoded, or an http(s) URL to download it from"
+
+// isMultipartMediaType reports whether a media type is multipart/form-data
+func isMultipartMediaType(contentType string) bool {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	return mediaType == "multipart/form-data"
+}
+
+// isBinarySchema reports whether a schema describes raw file content
+func isBinarySchema(schema *openapi3.Schema) bool {
+	return schema != nil && schema.Type == "string" && schema.Format == "binary"
+}
+
+// isBinaryContent reports whether a request body is sent as raw bytes: a
+// binary schema, or a media type other than JSON, forms and text without a
+// schema, e.g. application/octet-stream or image/png
+func isBinaryContent(contentType string, mediaType *openapi3.MediaType) bool {
+	if mediaType != nil && mediaType.Schema != nil && mediaType.Schema.Value != nil {
+		return isBinarySchema(mediaType.Schema.Value)
+	}
+	if isJSONMediaType(contentType) || isFormMediaType(contentType) || isMultipartMediaType(contentType) {
+		return false
+	}
+	return !strings.HasPrefix(strings.ToLower(contentType), "text/")
+}
+
+// binaryBodyArg creates the file argument carrying a raw binary body
+func binaryBodyArg(name string, requestBody *openapi3.RequestBody) models.Arg {
+	description := fileDescription
+	if requestBody.Description != "" {
+		description = requestBody.Description + ". " + fileDescription
+	}
+	return models.Arg{
+		Name:        name,
+		Description: description,
+		Type:        "string",
+		Required:    requestBody.Required,
+		Position:    "body",
+		Encoding:    models.FileEncoding,
+	}
+}
+
+// applyPartEncoding marks the file parts of a multipart body and keeps the
+// content type declared for a part in the encoding object
+func applyPartEncoding(arg *models.Arg, schema *openapi3.Schema, encoding *openapi3.Encoding) {
+	if encoding != nil && encoding.ContentType != "" {
+		arg.ContentType = encoding.ContentType
+	}
+
+	file := isBinarySchema(schema)
+	if schema.Type == "array" && schema.Items != nil && isBinarySchema(schema.Items.Value) {
+		// An array of files is sent as one part per file
+		file = true
+	}
+	if !file {
+		return
+	}
+
+	arg.Encoding = models.FileEncoding
+	if arg.Description == "" {
+		arg.Description = fileDescription
+	} else {
+		arg.Description += ". " + fileDescription
+	}
+}
+
+// defaultBodyArgName names the argument carrying a whole request body
+const defaultBodyArgName = "body"
+
+// bodyArgName returns the name of the argument carrying a whole request body
+func (c *Converter) bodyArgName() string {
+	if c.options.BodyArgName != "" {
+		return c.options.BodyArgName
+	}
+	return defaultBodyArgName
+}
+
+// isRawBody reports whether a request body is passed whole as a single
+// argument: JSON whose schema is an array or a primitive, or text. The type
+// may come from an allOf member.
+func (c *Converter) isRawBody(contentType string, mediaType *openapi3.MediaType) bool {
+	if strings.HasPrefix(strings.ToLower(contentType), "text/") {
+		return true
+	}
+	if !isJSONMediaType(contentType) || mediaType == nil || mediaType.Schema == nil || mediaType.Schema.Value == nil {
+		return false
+	}
+	switch c.flattenSchema(mediaType.Schema.Value).Type {
+	case "array", "string", "number", "integer", "boolean":
+		return true
+	}
+	return false
+}
+
+// rawBodyArg creates the argument carrying a whole request body, described
+// by the full body schema
+func (c *Converter) rawBodyArg(requestBody *openapi3.RequestBody, mediaType *openapi3.MediaType) (models.Arg, error) {
+	arg := models.Arg{
+		Name:        c.bodyArgName(),
+		Description: requestBody.Description,
+		Type:        "string",
+		Required:    requestBody.Required,
+		Position:    "body",
+	}
+	if mediaType == nil || mediaType.Schema == nil || mediaType.Schema.Value == nil {
+		return arg, nil
+	}
+	// The items of an array body may come from an allOf member
+	schema := c.flattenSchema(mediaType.Schema.Value)
+	if err := c.applySchemaToArg(&arg, schema); err != nil {
+		return arg, err
+	}
+	return arg, nil
+}
+
+// rawBodyTemplate returns the body template sending the whole body argument
+// name, encoded as JSON for JSON media types and verbatim otherwise
+func rawBodyTemplate(contentType, name string) string {
+	if isJSONMediaType(contentType) {
+		return "{{toJson .args." + name + "}}"
+	}
+	return "{{.args." + name + "}}"
+}
@@ -1,14 +1,17 @@
 package converter
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	texttemplate "text/template"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/higress-group/openapi-to-mcpserver/internal/models"
+	"github.com/higress-group/openapi-to-mcpserver/internal/naming"
 	"github.com/higress-group/openapi-to-mcpserver/internal/parser"
 )
 
@@ -22,6 +25,19 @@ const maxPropertyRecursionDepth = 10
 type Converter struct {
 	parser  *parser.Parser
 	options models.ConvertOptions
+	// flattened caches the allOf-merged form of composed schemas
+	flattened map[*openapi3.Schema]*openapi3.Schema
+	// securityKeys maps each supported security scheme to its server config keys
+	securityKeys map[string]map[string]string
+	// securityConfig collects the config placeholders of the applied security schemes
+	securityConfig map[string]interface{}
+	// warnings collects the notes about parts of the document left out of the conversion
+	warnings []string
+	// operations maps each converted tool to its operation
+	operations map[string]pathOperation
+	// responseTemplate renders the response descriptions when the options
+	// provide a response text template
+	responseTemplate *texttemplate.Template
 }
 
 // NewConverter creates a new OpenAPI to MCP converter
@@ -35,11 +51,22 @@ func NewConverter(parser *parser.Parser, options models.ConvertOptions) *Convert
 	}
 
 	return &Converter{
-		parser:  parser,
-		options: options,
+		parser:    parser,
+		options:   options,
+		flattened: make(map[*openapi3.Schema]*openapi3.Schema),
 	}
 }
 
+// ErrInvalidOptions is wrapped by the errors of conversion options that are
+// invalid or do not fit the document, e.g. an unknown body mode or a server
+// index out of range
+var ErrInvalidOptions = errors.New("invalid conversion options")
+
+// invalidOptions marks err as caused by the conversion options
+func invalidOptions(err error) error {
+	return fmt.Errorf("%w: %w", ErrInvalidOptions, err)
+}
+
 // Convert converts an OpenAPI document to an MCP configuration
 func (c *Converter) Convert() (*models.MCPConfig, error) {
 	if c.parser.GetDocument() == nil {
@@ -55,15 +82,82 @@ func (c *Converter) Convert() (*models.MCPConfig, error) {
 		Tools: []models.Tool{},
 	}
 
-	// Process each path and operation
-	for path, pathItem := range c.parser.GetPaths() {
-		operations := getOperations(pathItem)
-		for method, operation := range operations {
-			tool, err := c.convertOperation(path, method, operation)
-			if err != nil {
-				return nil, fmt.Errorf("failed to convert operation %s %s: %w", method, path, err)
-			}
-			config.Tools = append(config.Tools, *tool)
+	strategy, err := naming.NewStrategy(naming.Casing(c.options.ToolNameCasing), c.options.ToolNameMaxLength, c.options.ToolNamePrefix)
+	if err != nil {
+		return nil, invalidOptions(err)
+	}
+	names := naming.NewRegistry(strategy)
+
+	if err := c.validateServerSelection(); err != nil {
+		return nil, invalidOptions(err)
+	}
+
+	var template *configTemplate
+	if c.options.Template != "" {
+		if template, err = parseTemplate(c.options.Template); err != nil {
+			return nil, invalidOptions(err)
+		}
+	}
+
+	if err := c.validateBodyOptions(); err != nil {
+		return nil, invalidOptions(err)
+	}
+
+	c.responseTemplate = nil
+	if c.options.ResponseTextTemplate != "" {
+		if c.responseTemplate, err = c.parseResponseTemplate(c.options.ResponseTextTemplate); err != nil {
+			return nil, invalidOptions(err)
+		}
+	}
+
+	filter, err := newOperationFilter(c.options.Filter)
+	if err != nil {
+		return nil, invalidOptions(err)
+	}
+
+	c.securityKeys = c.securityConfigKeys()
+	c.securityConfig = make(map[string]interface{})
+	c.warnings = nil
+	c.operations = make(map[string]pathOperation)
+
+	// Process each path and operation in a stable order, so that tool name
+	// de-duplication gives the same result on every run
+	operations := sortedOperations(c.parser.GetPaths())
+	matches := 0
+	for _, op := range operations {
+		// Names are handed out before filtering, so that a tool keeps its name
+		// whichever subset of the document is converted
+		toolName := names.Unique(strategy.ToolName(op.operation.OperationID, op.method, op.path))
+		matched := filter.matches(op, toolName)
+		if matched {
+			matches++
+		}
+		if !matched && !c.options.Filter.AllowTools {
+			continue
+		}
+		if matched && c.options.Filter.AllowTools {
+			config.Server.AllowTools = append(config.Server.AllowTools, toolName)
+		}
+
+		tool, err := c.convertOperation(toolName, op.path, op.method, op.pathItem, op.operation)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert operation %s %s: %w", op.method, op.path, err)
+		}
+		config.Tools = append(config.Tools, *tool)
+		c.operations[toolName] = op
+	}
+
+	if matches == 0 && len(operations) > 0 {
+		// Also flags an empty allowTools list, which would allow every tool
+		c.warnings = append(c.warnings, "no operation matches the operation filter")
+	}
+
+	config.Server.Config = c.mergeSecurityConfig(config.Server.Config)
+
+	// Apply the template overlay last, so that it can extend every generated part
+	if template != nil {
+		if err := applyTemplate(config, template); err != nil {
+			return nil, err
 		}
 	}
 
@@ -71,10 +165,52 @@ func (c *Converter) Convert() (*models.MCPConfig, error) {
 	sort.Slice(config.Tools, func(i, j int) bool {
 		return config.Tools[i].Name < config.Tools[j].Name
 	})
+	sort.Strings(config.Server.AllowTools)
 
 	return config, nil
 }
 
+// Warnings returns the notes collected by the last conversion about parts of
+// the document that were left out, such as alternative request media types
+func (c *Converter) Warnings() []string {
+	return c.warnings
+}
+
+// httpMethods lists the HTTP methods of a path item in conversion order
+var httpMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// pathOperation is an operation together with its location in the document
+type pathOperation struct {
+	path      string
+	method    string
+	pathItem  *openapi3.PathItem
+	operation *openapi3.Operation
+}
+
+// sortedOperations lists every operation ordered by path, then by method
+func sortedOperations(paths openapi3.Paths) []pathOperation {
+	pathNames := make([]string, 0, len(paths))
+	for path := range paths {
+		pathNames = append(pathNames, path)
+	}
+	sort.Strings(pathNames)
+
+	var result []pathOperation
+	for _, path := range pathNames {
+		pathItem := paths[path]
+		if pathItem == nil {
+			continue
+		}
+		operations := getOperations(pathItem)
+		for _, method := range httpMethods {
+			if operation, ok := operations[method]; ok {
+				result = append(result, pathOperation{path: path, method: method, pathItem: pathItem, operation: operation})
+			}
+		}
+	}
+	return result
+}
+
 // getOperations returns a map of HTTP method to operation
 func getOperations(pathItem *openapi3.PathItem) map[string]*openapi3.Operation {
 	operations := make(map[string]*openapi3.Operation)
@@ -101,13 +237,7 @@ func getOperations(pathItem *openapi3.PathItem) map[string]*openapi3.Operation {
 }
 
 // convertOperation converts an OpenAPI operation to an MCP tool
-func (c *Converter) convertOperation(path, method string, operation *openapi3.Operation) (*models.Tool, error) {
-	// Generate a tool name
-	toolName := c.parser.GetOperationID(path, method, operation)
-	if c.options.ToolNamePrefix != "" {
-		toolName = c.options.ToolNamePrefix + toolName
-	}
-
+func (c *Converter) convertOperation(toolName, path, method string, pathItem *openapi3.PathItem, operation *openapi3.Operation) (*models.Tool, error) {
 	// Create the tool
 	tool := &models.Tool{
 		Name:        toolName,
@@ -115,19 +245,51 @@ func (c *Converter) convertOperation(path, method string, operation *openapi3.Op
 		Args:        []models.Arg{},
 	}
 
-	// Convert parameters to arguments
-	args, err := c.convertParameters(operation.Parameters)
+	// Convert parameters to arguments, including those shared by the whole path
+	args, err := c.convertParameters(mergeParameters(pathItem.Parameters, operation.Parameters))
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert parameters: %w", err)
 	}
-	tool.Args = append(tool.Args, args...)
 
 	// Convert request body to arguments
+	var contentType string
+	var mediaType *openapi3.MediaType
+	if operation.RequestBody != nil {
+		var dropped []string
+		contentType, mediaType, dropped = c.selectRequestContent(operation.RequestBody.Value)
+		if len(dropped) > 0 {
+			c.warnings = append(c.warnings, fmt.Sprintf("%s: using request content type %s, dropped %s", toolName, contentType, strings.Join(dropped, ", ")))
+		}
+	}
 	bodyArgs, err := c.convertRequestBody(operation.RequestBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert request body: %w", err)
 	}
-	tool.Args = append(tool.Args, bodyArgs...)
+
+	// JSON object bodies may be passed as a single argument holding the
+	// whole body, depending on the body mode and on name collisions
+	nested := false
+	if c.nestedBody(contentType, mediaType, args, bodyArgs) {
+		arg, err := c.rawBodyArg(operation.RequestBody.Value, mediaType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert request body: %w", err)
+		}
+		bodyArgs = []models.Arg{arg}
+		nested = true
+	}
+	wholeBody := nested || c.isRawBody(contentType, mediaType) || isBinaryContent(contentType, mediaType)
+	tool.Args = c.resolveArgNames(toolName, append(args, bodyArgs...), wholeBody)
+
+	// The body template refers to the argument holding the whole body by its
+	// final name
+	bodyTemplateArg := ""
+	if nested || c.isRawBody(contentType, mediaType) {
+		for _, arg := range tool.Args {
+			if arg.Position == "body" {
+				bodyTemplateArg = arg.Name
+			}
+		}
+	}
 
 	// Sort arguments by name for consistent output
 	sort.Slice(tool.Args, func(i, j int) bool {
@@ -135,24 +297,34 @@ func (c *Converter) convertOperation(path, method string, operation *openapi3.Op
 	})
 
 	// Create request template
-	requestTemplate, err := c.createRequestTemplate(path, method, operation)
+	requestTemplate, err := c.createRequestTemplate(path, method, pathItem, operation, bodyTemplateArg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request template: %w", err)
 	}
 	tool.RequestTemplate = *requestTemplate
+	c.warnSecurity(toolName, operation)
 
 	// Create response template
-	responseTemplate, err := c.createResponseTemplate(operation)
+	responseTemplate, err := c.createResponseTemplate(toolName, path, method, operation)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create response template: %w", err)
 	}
 	tool.ResponseTemplate = *responseTemplate
 
+	// Describe the behaviour of the tool for clients asking for confirmation
+	annotations, err := createAnnotations(method, operation)
+	if err != nil {
+		return nil, err
+	}
+	tool.Annotations = annotations
+
 	return tool, nil
 }
 
 // convertSchemaToProperties 将OpenAPI schema转换为属性映射
-func (c *Converter) convertSchemaToProperties(schema *openapi3.Schema, depth int) (map[string]interface{}, error) {
+// ancestors 记录当前展开路径上的 schema，用于在递归引用（如树节点引用自身）处停止展开
+// 属性映射用于请求参数，因此只读（readOnly）属性会被忽略
+func (c *Converter) convertSchemaToProperties(schema *openapi3.Schema, depth int, ancestors ...*openapi3.Schema) (map[string]interface{}, error) {
 	if schema == nil || len(schema.Properties) == 0 {
 		return nil, nil
 	}
@@ -160,6 +332,7 @@ func (c *Converter) convertSchemaToProperties(schema *openapi3.Schema, depth int
 	if depth > maxPropertyRecursionDepth {
 		return map[string]interface{}{"_note": "递归深度超过限制"}, nil
 	}
+	ancestors = append(ancestors, schema)
 
 	properties := make(map[string]interface{})
 	for propName, propRef := range schema.Properties {
@@ -167,7 +340,11 @@ func (c *Converter) convertSchemaToProperties(schema *openapi3.Schema, depth int
 			continue
 		}
 
-		propSchema := propRef.Value
+		propSchema := c.flattenSchema(propRef.Value)
+		// 只读属性只出现在响应中，不作为请求参数
+		if propSchema.ReadOnly {
+			continue
+		}
 		propInfo := map[string]interface{}{
 			"type": propSchema.Type,
 		}
@@ -177,34 +354,33 @@ func (c *Converter) convertSchemaToProperties(schema *openapi3.Schema, depth int
 			propInfo["description"] = propSchema.Description
 		}
 
+		// 处理可空类型
+		if propSchema.Nullable {
+			propInfo["nullable"] = true
+		}
+
 		// 处理枚举值
 		if len(propSchema.Enum) > 0 {
 			propInfo["enum"] = propSchema.Enum
 		}
 
+		// 处理格式、取值约束、默认值和示例
+		for keyword, value := range schemaConstraints(propSchema) {
+			propInfo[keyword] = value
+		}
+
 		// 处理数组类型
 		if propSchema.Type == "array" && propSchema.Items != nil && propSchema.Items.Value != nil {
-			itemsInfo := map[string]interface{}{
-				"type": propSchema.Items.Value.Type,
-			}
-
-			// 如果数组项是对象，递归处理其属性
-			if propSchema.Items.Value.Type == "object" && len(propSchema.Items.Value.Properties) > 0 {
-				nestedProps, err := c.convertSchemaToProperties(propSchema.Items.Value, depth+1)
-				if err != nil {
-					return nil, fmt.Errorf("处理数组项属性失败: %w", err)
-				}
-				if nestedProps != nil {
-					itemsInfo["properties"] = nestedProps
-				}
+			itemsInfo, err := c.itemsInfo(propSchema.Items.Value, depth+1, ancestors...)
+			if err != nil {
+				return nil, err
 			}
-
 			propInfo["items"] = itemsInfo
 		}
 
 		// 处理对象类型
-		if propSchema.Type == "object" && len(propSchema.Properties) > 0 {
-			nestedProps, err := c.convertSchemaToProperties(propSchema, depth+1)
+		if propSchema.Type == "object" && len(propSchema.Properties) > 0 && !isRecursive(propSchema, ancestors) {
+			nestedProps, err := c.convertSchemaToProperties(propSchema, depth+1, ancestors...)
 			if err != nil {
 				return nil, fmt.Errorf("处理嵌套属性失败: %w", err)
 			}
@@ -213,6 +389,28 @@ func (c *Converter) convertSchemaToProperties(schema *openapi3.Schema, depth int
 			}
 		}
 
+		// 处理对象的必填属性和额外属性
+		if propSchema.Type == "object" && !isRecursive(propSchema, ancestors) {
+			if err := c.addObjectKeywords(propInfo, propSchema, depth+1, ancestors...); err != nil {
+				return nil, err
+			}
+		}
+
+		// 处理 oneOf/anyOf 组合类型
+		if keyword, _ := schemaAlternatives(propSchema); keyword != "" {
+			alternatives, err := c.convertAlternatives(propSchema, depth, ancestors...)
+			if err != nil {
+				return nil, err
+			}
+			propInfo[keyword] = alternativesToInterfaces(alternatives)
+			if discriminator := discriminatorInfo(propSchema); discriminator != nil {
+				propInfo["discriminator"] = discriminator
+			}
+			if propSchema.Type == "" {
+				propInfo["type"] = c.commonType(propSchema)
+			}
+		}
+
 		properties[propName] = propInfo
 	}
 
@@ -233,37 +431,14 @@ func (c *Converter) convertParameters(parameters openapi3.Parameters) ([]models.
 			Name:        param.Name,
 			Description: param.Description,
 			Required:    param.Required,
+			Example:     param.Example,
 			Position:    param.In, // Set position based on parameter location (query, path, header, cookie)
 		}
 
 		// Set the type based on the schema
 		if param.Schema != nil && param.Schema.Value != nil {
-			schema := param.Schema.Value
-
-			// Set the type based on the schema type
-			arg.Type = schema.Type
-
-			// Handle enum values
-			if len(schema.Enum) > 0 {
-				arg.Enum = schema.Enum
-			}
-
-			// Handle array type
-			if schema.Type == "array" && schema.Items != nil && schema.Items.Value != nil {
-				arg.Items = map[string]interface{}{
-					"type": schema.Items.Value.Type,
-				}
-			}
-
-			// Handle object type
-			if schema.Type == "object" && len(schema.Properties) > 0 {
-				properties, err := c.convertSchemaToProperties(schema, 1)
-				if err != nil {
-					return nil, fmt.Errorf("转换参数属性失败: %w", err)
-				}
-				if properties != nil {
-					arg.Properties = properties
-				}
+			if err := c.applySchemaToArg(&arg, param.Schema.Value); err != nil {
+				return nil, fmt.Errorf("转换参数属性失败: %w", err)
 			}
 		}
 
@@ -273,6 +448,56 @@ func (c *Converter) convertParameters(parameters openapi3.Parameters) ([]models.
 	return args, nil
 }
 
+// setBodyEncoding tells the gateway how to serialize the body arguments for
+// a request content type. Arguments with a path, query, header or cookie
+// position keep their place; the flags only apply to the body.
+func setBodyEncoding(template *models.RequestTemplate, contentType string) {
+	switch {
+	case isJSONMediaType(contentType):
+		template.ArgsToJsonBody = true
+	case isFormMediaType(contentType):
+		template.ArgsToFormBody = true
+	}
+}
+
+// isJSONMediaType reports whether a media type carries JSON, including
+// structured syntax types such as application/merge-patch+json
+func isJSONMediaType(contentType string) bool {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// isFormMediaType reports whether a media type is a URL-encoded form
+func isFormMediaType(contentType string) bool {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	return mediaType == "application/x-www-form-urlencoded"
+}
+
+// mergeParameters combines path-level and operation-level parameters. A
+// parameter is identified by its name and location; operation-level
+// definitions override path-level ones with the same identity.
+func mergeParameters(pathParams, operationParams openapi3.Parameters) openapi3.Parameters {
+	if len(pathParams) == 0 {
+		return operationParams
+	}
+
+	overridden := make(map[string]bool, len(operationParams))
+	for _, paramRef := range operationParams {
+		if paramRef != nil && paramRef.Value != nil {
+			overridden[paramRef.Value.In+":"+paramRef.Value.Name] = true
+		}
+	}
+
+	merged := make(openapi3.Parameters, 0, len(pathParams)+len(operationParams))
+	for _, paramRef := range pathParams {
+		if paramRef == nil || paramRef.Value == nil || overridden[paramRef.Value.In+":"+paramRef.Value.Name] {
+			continue
+		}
+		merged = append(merged, paramRef)
+	}
+	return append(merged, operationParams...)
+}
+
 // convertRequestBody converts an OpenAPI request body to MCP arguments
 func (c *Converter) convertRequestBody(requestBodyRef *openapi3.RequestBodyRef) ([]models.Arg, error) {
 	args := []models.Arg{}
@@ -281,73 +506,79 @@ func (c *Converter) convertRequestBody(requestBodyRef *openapi3.RequestBodyRef)
 		return args, nil
 	}
 
-	requestBody := requestBodyRef.Value
+	// Only the selected content type is converted, so that args are not
+	// duplicated across alternative media types
+	contentType, mediaType, _ := c.selectRequestContent(requestBodyRef.Value)
+
+	// Binary content such as application/octet-stream is a single file argument
+	if isBinaryContent(contentType, mediaType) {
+		return append(args, binaryBodyArg(c.bodyArgName(), requestBodyRef.Value)), nil
+	}
+
+	// Arrays, primitives and text are passed whole as a single argument
+	if c.isRawBody(contentType, mediaType) {
+		arg, err := c.rawBodyArg(requestBodyRef.Value, mediaType)
+		if err != nil {
+			return nil, fmt.Errorf("转换请求体失败: %w", err)
+		}
+		return append(args, arg), nil
+	}
+	if mediaType == nil || mediaType.Schema == nil || mediaType.Schema.Value == nil {
+		return args, nil
+	}
+
+	// For JSON, form and multipart content types, convert the schema to arguments
+	multipart := isMultipartMediaType(contentType)
+	if !isJSONMediaType(contentType) && !isFormMediaType(contentType) && !multipart {
+		return args, nil
+	}
+	schema := c.flattenSchema(mediaType.Schema.Value)
 
-	// Process each content type
-	for contentType, mediaType := range requestBody.Content {
-		if mediaType.Schema == nil || mediaType.Schema.Value == nil {
+	// For object type, convert each property to an argument
+	properties, required, owners := c.bodyProperties(schema)
+	for propName, propRef := range properties {
+		// Read-only properties are set by the server and never sent
+		if propRef.Value == nil || c.flattenSchema(propRef.Value).ReadOnly {
 			continue
 		}
 
-		schema := mediaType.Schema.Value
-
-		// For JSON and form content types, convert the schema to arguments
-		if strings.Contains(contentType, "application/json") ||
-			strings.Contains(contentType, "application/x-www-form-urlencoded") {
-
-			// For object type, convert each property to an argument
-			if schema.Type == "object" && len(schema.Properties) > 0 {
-				for propName, propRef := range schema.Properties {
-					if propRef.Value == nil {
-						continue
-					}
-
-					arg := models.Arg{
-						Name:        propName,
-						Description: propRef.Value.Description,
-						Type:        propRef.Value.Type,
-						Required:    contains(schema.Required, propName),
-						Position:    "body", // Set position to "body" for request body parameters
-					}
-
-					// Handle enum values
-					if len(propRef.Value.Enum) > 0 {
-						arg.Enum = propRef.Value.Enum
-					}
-
-					// Handle array type
-					if propRef.Value.Type == "array" && propRef.Value.Items != nil && propRef.Value.Items.Value != nil {
-						arg.Items = map[string]interface{}{
-							"type": propRef.Value.Items.Value.Type,
-						}
-					}
-
-					// Handle object type
-					if propRef.Value.Type == "object" && len(propRef.Value.Properties) > 0 {
-						properties, err := c.convertSchemaToProperties(propRef.Value, 1)
-						if err != nil {
-							return nil, fmt.Errorf("转换请求体属性失败: %w", err)
-						}
-						if properties != nil {
-							arg.Properties = properties
-						}
-					}
-
-					args = append(args, arg)
-				}
+		arg := models.Arg{
+			Name:        propName,
+			Description: propRef.Value.Description,
+			Required:    contains(required, propName),
+			Position:    "body", // Set position to "body" for request body parameters
+		}
+
+		if err := c.applySchemaToArg(&arg, propRef.Value, owners[propName]); err != nil {
+			return nil, fmt.Errorf("转换请求体属性失败: %w", err)
+		}
+
+		// Multipart parts may be files and keep their declared content type
+		if multipart {
+			applyPartEncoding(&arg, propRef.Value, mediaType.Encoding[propName])
+		}
+
+		// The discriminator of a polymorphic body only accepts the mapped values
+		if discriminator := schema.Discriminator; discriminator != nil && discriminator.PropertyName == propName && len(arg.Enum) == 0 {
+			for _, value := range sortedMapKeys(discriminatorMapping(schema)) {
+				arg.Enum = append(arg.Enum, value)
 			}
 		}
+
+		args = append(args, arg)
 	}
 
 	return args, nil
 }
 
-// createRequestTemplate creates an MCP request template from an OpenAPI operation
-func (c *Converter) createRequestTemplate(path, method string, operation *openapi3.Operation) (*models.RequestTemplate, error) {
+// createRequestTemplate creates an MCP request template from an OpenAPI
+// operation. bodyArg names the argument sent as the whole request body, and
+// is empty when the body is built from the arguments by name.
+func (c *Converter) createRequestTemplate(path, method string, pathItem *openapi3.PathItem, operation *openapi3.Operation, bodyArg string) (*models.RequestTemplate, error) {
 	// Get the server URL from the OpenAPI specification
-	var serverURL string
-	if servers := c.parser.GetDocument().Servers; len(servers) > 0 {
-		serverURL = servers[0].URL
+	serverURL, err := c.serverURL(pathItem, operation)
+	if err != nil {
+		return nil, err
 	}
 
 	// Remove trailing slash from server URL if present
@@ -361,41 +592,58 @@ func (c *Converter) createRequestTemplate(path, method string, operation *openap
 	}
 
 	// Add Content-Type header based on request body content type
-	if operation.RequestBody != nil && operation.RequestBody.Value != nil {
-		for contentType := range operation.RequestBody.Value.Content {
-			// Add the Content-Type header
+	if operation.RequestBody != nil {
+		if contentType, _, _ := c.selectRequestContent(operation.RequestBody.Value); contentType != "" {
 			template.Headers = append(template.Headers, models.Header{
 				Key:   "Content-Type",
 				Value: contentType,
 			})
-			break // Just use the first content type
+			if bodyArg != "" {
+				template.Body = rawBodyTemplate(contentType, bodyArg)
+			} else {
+				setBodyEncoding(template, contentType)
+			}
 		}
 	}
 
+	// Add the credentials required by the operation
+	c.applySecurity(template, operation)
+
 	return template, nil
 }
 
 // createResponseTemplate creates an MCP response template from an OpenAPI operation
-func (c *Converter) createResponseTemplate(operation *openapi3.Operation) (*models.ResponseTemplate, error) {
-	// Find the success response (200, 201, etc.)
-	var successResponse *openapi3.Response
-
-	if operation.Responses != nil {
-		for code, responseRef := range operation.Responses {
-			if strings.HasPrefix(code, "2") && responseRef != nil && responseRef.Value != nil {
-				successResponse = responseRef.Value
-				break
-			}
+func (c *Converter) createResponseTemplate(toolName, path, method string, operation *openapi3.Operation) (*models.ResponseTemplate, error) {
+	template := &models.ResponseTemplate{}
+
+	// Find the success response (200, then 201, etc.) and record it
+	statusCode, response := successResponse(operation)
+	var contentType string
+	var mediaType *openapi3.MediaType
+	if response != nil {
+		template.StatusCode = statusCode
+		contentType, mediaType = selectResponseContent(response)
+		template.ContentType = contentType
+	}
+
+	// A response text template fully controls the description
+	if c.responseTemplate != nil {
+		if err := c.renderResponseTemplate(template, c.responseTemplateData(toolName, path, method, operation)); err != nil {
+			return nil, err
 		}
+		return template, nil
 	}
 
-	// If there's no success response, don't add a response template
-	if successResponse == nil || len(successResponse.Content) == 0 {
-		return &models.ResponseTemplate{}, nil
+	// Describe the error responses when requested
+	var errorResponses string
+	if c.options.DescribeErrorResponses {
+		errorResponses = c.describeErrorResponses(operation)
 	}
 
-	// Create the response template
-	template := &models.ResponseTemplate{}
+	// If there is no content to describe, don't add a description
+	if mediaType == nil && errorResponses == "" {
+		return template, nil
+	}
 
 	// 初始化一个字符串构建器用于生成响应模板内容
 	var prependBody strings.Builder
@@ -421,70 +669,82 @@ func (c *Converter) createResponseTemplate(operation *openapi3.Operation) (*mode
 		}
 	}
 
-	// Process each content type
-	for contentType, mediaType := range successResponse.Content {
-		if mediaType.Schema == nil || mediaType.Schema.Value == nil {
-			continue
-		}
-
+	// Describe the fields of the selected content type
+	if mediaType != nil && mediaType.Schema != nil && mediaType.Schema.Value != nil {
 		prependBody.WriteString(fmt.Sprintf("> Content-Type: %s\n\n", contentType))
-		schema := mediaType.Schema.Value
-
-		// Generate field descriptions using recursive function
-		if schema.Type == "array" && schema.Items != nil && schema.Items.Value != nil {
-			// Handle array type
-			prependBody.WriteString(fmt.Sprintf("- **items**: Array of items (Type: array)\n"))
-			// Process array items recursively
-			c.processSchemaProperties(&prependBody, schema.Items.Value, "items", 1, maxPropertyRecursionDepth)
-		} else if schema.Type == "object" && len(schema.Properties) > 0 {
-			// Get property names and sort them alphabetically for consistent output
-			propNames := make([]string, 0, len(schema.Properties))
-			for propName := range schema.Properties {
-				propNames = append(propNames, propName)
-			}
-			sort.Strings(propNames)
+		c.describeSchemaFields(&prependBody, mediaType.Schema.Value)
+	}
 
-			// Process properties in alphabetical order
-			for _, propName := range propNames {
-				propRef := schema.Properties[propName]
-				if propRef.Value == nil {
-					continue
-				}
+	prependBody.WriteString(errorResponses)
+	prependBody.WriteString("\n## Original Response\n\n")
+	template.PrependBody = prependBody.String()
 
-				// Write the property description
-				prependBody.WriteString(fmt.Sprintf("- **%s**: %s", propName, propRef.Value.Description))
-				if propRef.Value.Type != "" {
-					prependBody.WriteString(fmt.Sprintf(" (Type: %s)", propRef.Value.Type))
-				}
-				prependBody.WriteString("\n")
+	return template, nil
+}
 
-				// Process nested properties recursively
-				c.processSchemaProperties(&prependBody, propRef.Value, propName, 1, maxPropertyRecursionDepth)
+// describeSchemaFields writes the field list of a response schema: the
+// properties of an object, the items of an array and the alternatives of a
+// polymorphic schema
+func (c *Converter) describeSchemaFields(b *strings.Builder, schema *openapi3.Schema) {
+	schema = c.flattenSchema(schema)
+
+	// Generate field descriptions using recursive function
+	if schema.Type == "array" && schema.Items != nil && schema.Items.Value != nil {
+		// Handle array type
+		b.WriteString(fmt.Sprintf("- **items**: Array of items (Type: array)\n"))
+		// Process array items recursively
+		c.processSchemaProperties(b, schema.Items.Value, "items", 1, maxPropertyRecursionDepth)
+	} else if schema.Type == "object" && len(schema.Properties) > 0 {
+		// Get property names and sort them alphabetically for consistent output
+		propNames := make([]string, 0, len(schema.Properties))
+		for propName := range schema.Properties {
+			propNames = append(propNames, propName)
+		}
+		sort.Strings(propNames)
+
+		// Process properties in alphabetical order
+		for _, propName := range propNames {
+			propRef := schema.Properties[propName]
+			if propRef.Value == nil {
+				continue
 			}
+			propSchema := c.flattenSchema(propRef.Value)
+
+			// Write the property description
+			b.WriteString(fmt.Sprintf("- **%s**: %s", propName, propSchema.Description))
+			if propSchema.Type != "" {
+				b.WriteString(fmt.Sprintf(" (Type: %s)", propSchema.Type))
+			}
+			b.WriteString("\n")
+
+			// Process nested properties recursively
+			c.processSchemaProperties(b, propSchema, propName, 1, maxPropertyRecursionDepth, schema)
 		}
 	}
 
-	prependBody.WriteString("\n## Original Response\n\n")
-	template.PrependBody = prependBody.String()
-
-	return template, nil
+	// Describe polymorphic (oneOf/anyOf) responses
+	c.processSchemaAlternatives(b, schema, "", 0, maxPropertyRecursionDepth)
 }
 
 // processSchemaProperties recursively processes schema properties and writes them to the prependBody
 // path is the current property path (e.g., "data.items")
 // depth is the current nesting depth (starts at 1)
 // maxDepth is the maximum allowed nesting depth
-func (c *Converter) processSchemaProperties(prependBody *strings.Builder, schema *openapi3.Schema, path string, depth, maxDepth int) {
-	if depth > maxDepth {
-		return // Stop recursion if max depth is reached
+// ancestors holds the schemas already expanded on the current path; a schema
+// that refers back to one of them is described but not expanded again
+func (c *Converter) processSchemaProperties(prependBody *strings.Builder, schema *openapi3.Schema, path string, depth, maxDepth int, ancestors ...*openapi3.Schema) {
+	schema = c.flattenSchema(schema)
+	if depth > maxDepth || isRecursive(schema, ancestors) {
+		return // Stop recursion if max depth is reached or the schema is recursive
 	}
+	ancestors = append(ancestors, schema)
 
 	// Calculate indentation based on depth
 	indent := strings.Repeat("  ", depth)
 
 	// Handle array type
 	if schema.Type == "array" && schema.Items != nil && schema.Items.Value != nil {
-		arrayItemSchema := schema.Items.Value
+		arrayItemSchema := c.flattenSchema(schema.Items.Value)
 
 		// Include the array description if available
 		arrayDesc := schema.Description
@@ -493,7 +753,7 @@ func (c *Converter) processSchemaProperties(prependBody *strings.Builder, schema
 		}
 
 		// If array items are objects, describe their properties
-		if arrayItemSchema.Type == "object" && len(arrayItemSchema.Properties) > 0 {
+		if arrayItemSchema.Type == "object" && len(arrayItemSchema.Properties) > 0 && !isRecursive(arrayItemSchema, ancestors) {
 			// Sort property names for consistent output
 			propNames := make([]string, 0, len(arrayItemSchema.Properties))
 			for propName := range arrayItemSchema.Properties {
@@ -507,18 +767,22 @@ func (c *Converter) processSchemaProperties(prependBody *strings.Builder, schema
 				if propRef.Value == nil {
 					continue
 				}
+				propSchema := c.flattenSchema(propRef.Value)
 
 				// Write the property description
 				propPath := fmt.Sprintf("%s[].%s", path, propName)
-				prependBody.WriteString(fmt.Sprintf("%s- **%s**: %s", indent, propPath, propRef.Value.Description))
-				if propRef.Value.Type != "" {
-					prependBody.WriteString(fmt.Sprintf(" (Type: %s)", propRef.Value.Type))
+				prependBody.WriteString(fmt.Sprintf("%s- **%s**: %s", indent, propPath, propSchema.Description))
+				if propSchema.Type != "" {
+					prependBody.WriteString(fmt.Sprintf(" (Type: %s)", propSchema.Type))
 				}
 				prependBody.WriteString("\n")
 
 				// Process nested properties recursively
-				c.processSchemaProperties(prependBody, propRef.Value, propPath, depth+1, maxDepth)
+				c.processSchemaProperties(prependBody, propSchema, propPath, depth+1, maxDepth, append(ancestors, arrayItemSchema)...)
 			}
+		} else if keyword, _ := schemaAlternatives(arrayItemSchema); keyword != "" {
+			// If array items are polymorphic, describe each alternative
+			c.processSchemaAlternatives(prependBody, arrayItemSchema, path+"[]", depth, maxDepth, ancestors...)
 		} else if arrayItemSchema.Type != "" {
 			// If array items are not objects, just describe the array item type
 			prependBody.WriteString(fmt.Sprintf("%s- **%s[]**: Items of type %s\n", indent, path, arrayItemSchema.Type))
@@ -541,19 +805,79 @@ func (c *Converter) processSchemaProperties(prependBody *strings.Builder, schema
 			if propRef.Value == nil {
 				continue
 			}
+			propSchema := c.flattenSchema(propRef.Value)
 
 			// Write the property description
-			propPath := fmt.Sprintf("%s.%s", path, propName)
-			prependBody.WriteString(fmt.Sprintf("%s- **%s**: %s", indent, propPath, propRef.Value.Description))
-			if propRef.Value.Type != "" {
-				prependBody.WriteString(fmt.Sprintf(" (Type: %s)", propRef.Value.Type))
+			propPath := joinPropertyPath(path, propName)
+			prependBody.WriteString(fmt.Sprintf("%s- **%s**: %s", indent, propPath, propSchema.Description))
+			if propSchema.Type != "" {
+				prependBody.WriteString(fmt.Sprintf(" (Type: %s)", propSchema.Type))
 			}
 			prependBody.WriteString("\n")
 
 			// Process nested properties recursively
-			c.processSchemaProperties(prependBody, propRef.Value, propPath, depth+1, maxDepth)
+			c.processSchemaProperties(prependBody, propSchema, propPath, depth+1, maxDepth, ancestors...)
 		}
 	}
+
+	// Handle oneOf/anyOf alternatives
+	c.processSchemaAlternatives(prependBody, schema, path, depth, maxDepth, ancestors...)
+}
+
+// processSchemaAlternatives describes the oneOf/anyOf alternatives of a schema.
+// Each alternative is listed by name, with the discriminator values selecting
+// it, followed by its own properties. An empty path denotes the response root.
+func (c *Converter) processSchemaAlternatives(prependBody *strings.Builder, schema *openapi3.Schema, path string, depth, maxDepth int, ancestors ...*openapi3.Schema) {
+	keyword, alternatives := schemaAlternatives(schema)
+	if keyword == "" || depth > maxDepth {
+		return
+	}
+	ancestors = append(ancestors, schema)
+
+	indent := strings.Repeat("  ", depth)
+	label := "one of"
+	if keyword == "anyOf" {
+		label = "any of"
+	}
+	if path == "" {
+		prependBody.WriteString(fmt.Sprintf("%s- The response is %s:\n", indent, label))
+	} else {
+		prependBody.WriteString(fmt.Sprintf("%s- **%s** is %s:\n", indent, path, label))
+	}
+
+	for i, altRef := range alternatives {
+		if altRef == nil || altRef.Value == nil {
+			continue
+		}
+		altSchema := c.flattenSchema(altRef.Value)
+
+		title := schemaTitle(altRef, altSchema)
+		if title == "" {
+			title = fmt.Sprintf("Option %d", i+1)
+		}
+		prependBody.WriteString(fmt.Sprintf("%s  - **%s**", indent, title))
+		if values := discriminatorValues(schema, altRef); len(values) > 0 {
+			prependBody.WriteString(fmt.Sprintf(" (%s: %s)", schema.Discriminator.PropertyName, strings.Join(values, ", ")))
+		}
+		if altSchema.Description != "" {
+			prependBody.WriteString(": " + altSchema.Description)
+		}
+		if altSchema.Type != "" {
+			prependBody.WriteString(fmt.Sprintf(" (Type: %s)", altSchema.Type))
+		}
+		prependBody.WriteString("\n")
+
+		// Describe the properties of the alternative
+		c.processSchemaProperties(prependBody, altSchema, path, depth+2, maxDepth, ancestors...)
+	}
+}
+
+// joinPropertyPath appends a property name to a property path
+func joinPropertyPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
 }
 
 // getDescription returns a description for an operation
@@ -567,6 +891,17 @@ func getDescription(operation *openapi3.Operation) string {
 	return operation.Description
 }
 
+// isRecursive reports whether schema is already being expanded further up the
+// current path, which happens when a $ref points back to an enclosing schema
+func isRecursive(schema *openapi3.Schema, ancestors []*openapi3.Schema) bool {
+	for _, ancestor := range ancestors {
+		if ancestor == schema {
+			return true
+		}
+	}
+	return false
+}
+
 // contains checks if a string slice contains a string
 func contains(slice []string, str string) bool {
 	for _, s := range slice {
@@ -0,0 +1,144 @@
+package converter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/higress-group/openapi-to-mcpserver/internal/models"
+)
+
+// operationFilter is the compiled form of models.OperationFilter
+type operationFilter struct {
+	options            models.OperationFilter
+	includePaths       []*regexp.Regexp
+	excludePaths       []*regexp.Regexp
+	includeOperationID *regexp.Regexp
+	excludeOperationID *regexp.Regexp
+}
+
+// newOperationFilter compiles the path globs and operationId expressions of
+// the filter options
+func newOperationFilter(options models.OperationFilter) (*operationFilter, error) {
+	filter := &operationFilter{
+		options:      options,
+		includePaths: compilePathGlobs(options.IncludePaths),
+		excludePaths: compilePathGlobs(options.ExcludePaths),
+	}
+	var err error
+	if options.IncludeOperationID != "" {
+		if filter.includeOperationID, err = regexp.Compile(options.IncludeOperationID); err != nil {
+			return nil, fmt.Errorf("invalid operationId include pattern: %w", err)
+		}
+	}
+	if options.ExcludeOperationID != "" {
+		if filter.excludeOperationID, err = regexp.Compile(options.ExcludeOperationID); err != nil {
+			return nil, fmt.Errorf("invalid operationId exclude pattern: %w", err)
+		}
+	}
+	return filter, nil
+}
+
+// matches reports whether the operation converted to toolName passes the
+// filter: it must match every include criterion given and no exclude one
+func (f *operationFilter) matches(op pathOperation, toolName string) bool {
+	if f.options.ExcludeDeprecated && op.operation.Deprecated {
+		return false
+	}
+
+	if len(f.options.IncludeTags) > 0 && !containsAny(f.options.IncludeTags, op.operation.Tags) {
+		return false
+	}
+	if containsAny(f.options.ExcludeTags, op.operation.Tags) {
+		return false
+	}
+
+	if len(f.includePaths) > 0 && !matchesAny(f.includePaths, op.path) {
+		return false
+	}
+	if matchesAny(f.excludePaths, op.path) {
+		return false
+	}
+
+	if len(f.options.IncludeMethods) > 0 && !containsFold(f.options.IncludeMethods, op.method) {
+		return false
+	}
+	if containsFold(f.options.ExcludeMethods, op.method) {
+		return false
+	}
+
+	operationID := op.operation.OperationID
+	if operationID == "" {
+		operationID = toolName
+	}
+	if f.includeOperationID != nil && !f.includeOperationID.MatchString(operationID) {
+		return false
+	}
+	if f.excludeOperationID != nil && f.excludeOperationID.MatchString(operationID) {
+		return false
+	}
+
+	return true
+}
+
+// compilePathGlobs turns path globs into anchored regular expressions. *
+// matches within a single path segment and ** across any number of segments.
+func compilePathGlobs(globs []string) []*regexp.Regexp {
+	patterns := make([]*regexp.Regexp, 0, len(globs))
+	for _, glob := range globs {
+		var b strings.Builder
+		b.WriteString("^")
+		for i := 0; i < len(glob); i++ {
+			switch {
+			case strings.HasPrefix(glob[i:], "/**"):
+				// /pets/** matches /pets itself as well as everything below it
+				b.WriteString("(/.*)?")
+				i += 2
+			case strings.HasPrefix(glob[i:], "**"):
+				b.WriteString(".*")
+				i++
+			case glob[i] == '*':
+				b.WriteString("[^/]*")
+			case glob[i] == '?':
+				b.WriteString("[^/]")
+			default:
+				b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+			}
+		}
+		b.WriteString("$")
+		patterns = append(patterns, regexp.MustCompile(b.String()))
+	}
+	return patterns
+}
+
+// matchesAny reports whether path matches one of the patterns
+func matchesAny(patterns []*regexp.Regexp, path string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsAny reports whether values and candidates have an element in common
+func containsAny(values, candidates []string) bool {
+	for _, candidate := range candidates {
+		for _, value := range values {
+			if value == candidate {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// containsFold reports whether values contains s, ignoring case
+func containsFold(values []string, s string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, s) {
+			return true
+		}
+	}
+	return false
+}
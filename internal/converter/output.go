@@ -0,0 +1,140 @@
+package converter
+
+import (
+	"fmt"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/higress-group/openapi-to-mcpserver/internal/models"
+)
+
+// ConvertOutput converts the document in the output mode of the options:
+// a Higress configuration (*models.MCPConfig) or MCP tool definitions
+// (*models.MCPToolList)
+func (c *Converter) ConvertOutput() (interface{}, error) {
+	switch c.options.OutputMode {
+	case "", models.OutputModeHigress:
+		return c.Convert()
+	case models.OutputModeMCP:
+		return c.ConvertToolDefinitions()
+	default:
+		return nil, invalidOptions(fmt.Errorf("unsupported output mode %q", c.options.OutputMode))
+	}
+}
+
+// ConvertToolDefinitions converts the document into MCP tool definitions
+// with a JSON Schema of the arguments and, for JSON object responses, of
+// the successful result
+func (c *Converter) ConvertToolDefinitions() (*models.MCPToolList, error) {
+	config, err := c.Convert()
+	if err != nil {
+		return nil, err
+	}
+
+	list := &models.MCPToolList{Tools: make([]models.MCPTool, 0, len(config.Tools))}
+	for i := range config.Tools {
+		tool := &config.Tools[i]
+		definition := models.MCPTool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: tool.InputSchema(),
+			Annotations: tool.Annotations,
+		}
+		if op, ok := c.operations[tool.Name]; ok {
+			definition.OutputSchema = c.outputSchema(op.operation)
+		}
+		list.Tools = append(list.Tools, definition)
+	}
+	return list, nil
+}
+
+// outputSchema returns the JSON Schema of the successful response of an
+// operation. MCP requires output schemas to describe objects, so responses
+// of other types, and responses without a JSON schema, have none.
+func (c *Converter) outputSchema(operation *openapi3.Operation) map[string]interface{} {
+	_, response := successResponse(operation)
+	if response == nil {
+		return nil
+	}
+
+	contentType, mediaType := selectResponseContent(response)
+	if !isJSONMediaType(contentType) || mediaType.Schema == nil || mediaType.Schema.Value == nil {
+		return nil
+	}
+	schema := c.flattenSchema(mediaType.Schema.Value)
+	if schema.Type != "object" {
+		return nil
+	}
+	return models.ToJSONSchema(c.schemaMap(schema, 0))
+}
+
+// schemaMap describes a schema as a map using OpenAPI 3.0 keywords,
+// including read-only properties. Recursive references and schemas nested
+// deeper than maxPropertyRecursionDepth are cut off with their type only.
+func (c *Converter) schemaMap(schema *openapi3.Schema, depth int, ancestors ...*openapi3.Schema) map[string]interface{} {
+	schema = c.flattenSchema(schema)
+	result := map[string]interface{}{}
+	if schema.Type != "" {
+		result["type"] = schema.Type
+	}
+	if schema.Title != "" {
+		result["title"] = schema.Title
+	}
+	if schema.Description != "" {
+		result["description"] = schema.Description
+	}
+	if schema.Nullable {
+		result["nullable"] = true
+	}
+	if len(schema.Enum) > 0 {
+		result["enum"] = schema.Enum
+	}
+	if schema.ReadOnly {
+		result["readOnly"] = true
+	}
+	for keyword, value := range schemaConstraints(schema) {
+		result[keyword] = value
+	}
+
+	if isRecursive(schema, ancestors) || depth >= maxPropertyRecursionDepth {
+		return result
+	}
+	ancestors = append(ancestors, schema)
+
+	if len(schema.Properties) > 0 {
+		properties := make(map[string]interface{}, len(schema.Properties))
+		for name, propRef := range schema.Properties {
+			if propRef != nil && propRef.Value != nil {
+				properties[name] = c.schemaMap(propRef.Value, depth+1, ancestors...)
+			}
+		}
+		result["properties"] = properties
+	}
+	if len(schema.Required) > 0 {
+		result["required"] = schema.Required
+	}
+	if additional := schema.AdditionalProperties; additional.Schema != nil && additional.Schema.Value != nil {
+		result["additionalProperties"] = c.schemaMap(additional.Schema.Value, depth+1, ancestors...)
+	} else if additional.Has != nil {
+		result["additionalProperties"] = *additional.Has
+	}
+	if schema.Items != nil && schema.Items.Value != nil {
+		result["items"] = c.schemaMap(schema.Items.Value, depth+1, ancestors...)
+	}
+	if keyword, alternatives := schemaAlternatives(schema); keyword != "" {
+		list := make([]interface{}, 0, len(alternatives))
+		for _, altRef := range alternatives {
+			if altRef != nil && altRef.Value != nil {
+				alternative := c.schemaMap(altRef.Value, depth+1, ancestors...)
+				if title := schemaTitle(altRef, c.flattenSchema(altRef.Value)); title != "" {
+					alternative["title"] = title
+				}
+				list = append(list, alternative)
+			}
+		}
+		result[keyword] = list
+		if discriminator := discriminatorInfo(schema); discriminator != nil {
+			result["discriminator"] = discriminator
+		}
+	}
+	return result
+}
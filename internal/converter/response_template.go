@@ -0,0 +1,286 @@
+package converter
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/higress-group/openapi-to-mcpserver/internal/models"
+)
+
+// appendBodyTemplate names the template defining the appendBody of a
+// response text template
+const appendBodyTemplate = "appendBody"
+
+// responseTemplateData is the data response text templates are rendered with
+type responseTemplateData struct {
+	Operation operationInfo
+	// StatusCode, ContentType, Description and Schema describe the selected
+	// success response; Schema is nil when it has no schema
+	StatusCode  string
+	ContentType string
+	Description string
+	Schema      *openapi3.Schema
+	// ErrorResponses lists the other responses in status code order
+	ErrorResponses []responseInfo
+}
+
+// operationInfo describes the operation of a tool to response text templates
+type operationInfo struct {
+	ToolName    string
+	OperationID string
+	Summary     string
+	Description string
+	Method      string
+	Path        string
+	Tags        []string
+	Deprecated  bool
+}
+
+// responseInfo describes a response to response text templates
+type responseInfo struct {
+	StatusCode  string
+	ContentType string
+	Description string
+	Schema      *openapi3.Schema
+}
+
+// parseResponseTemplate parses the Go text/template used to generate the
+// response descriptions of all tools
+func (c *Converter) parseResponseTemplate(content string) (*template.Template, error) {
+	tmpl, err := template.New("response").Funcs(c.responseTemplateFuncs()).Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// responseTemplateFuncs returns the helper functions of response text
+// templates
+func (c *Converter) responseTemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		// fieldList renders the fields of a schema as the nested markdown list
+		// of the default response description
+		"fieldList": func(schema *openapi3.Schema) string {
+			if schema == nil {
+				return ""
+			}
+			var b strings.Builder
+			c.describeSchemaFields(&b, schema)
+			return b.String()
+		},
+		"fieldTable":  c.fieldTable,
+		"jsonExample": c.jsonExample,
+		"toJson": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+		"join":  func(items []string, sep string) string { return strings.Join(items, sep) },
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+		"statusText": func(code string) string {
+			status, err := strconv.Atoi(code)
+			if err != nil {
+				return ""
+			}
+			return http.StatusText(status)
+		},
+	}
+}
+
+// renderResponseTemplate fills a response template from the response text
+// template: the template output becomes prependBody and the output of its
+// appendBody template, when defined, appendBody
+func (c *Converter) renderResponseTemplate(target *models.ResponseTemplate, data responseTemplateData) error {
+	var prependBody strings.Builder
+	if err := c.responseTemplate.Execute(&prependBody, data); err != nil {
+		return invalidOptions(fmt.Errorf("failed to render response template: %w", err))
+	}
+	target.PrependBody = prependBody.String()
+
+	if c.responseTemplate.Lookup(appendBodyTemplate) == nil {
+		return nil
+	}
+	var appendBody strings.Builder
+	if err := c.responseTemplate.ExecuteTemplate(&appendBody, appendBodyTemplate, data); err != nil {
+		return invalidOptions(fmt.Errorf("failed to render response template: %w", err))
+	}
+	target.AppendBody = appendBody.String()
+	return nil
+}
+
+// responseTemplateData collects the data of the response text template of
+// an operation
+func (c *Converter) responseTemplateData(toolName, path, method string, operation *openapi3.Operation) responseTemplateData {
+	data := responseTemplateData{
+		Operation: operationInfo{
+			ToolName:    toolName,
+			OperationID: operation.OperationID,
+			Summary:     operation.Summary,
+			Description: operation.Description,
+			Method:      strings.ToUpper(method),
+			Path:        path,
+			Tags:        operation.Tags,
+			Deprecated:  operation.Deprecated,
+		},
+	}
+
+	successCode, response := successResponse(operation)
+	if response != nil {
+		info := c.responseInfo(successCode, response)
+		data.StatusCode = info.StatusCode
+		data.ContentType = info.ContentType
+		data.Description = info.Description
+		data.Schema = info.Schema
+	}
+	if operation.Responses != nil {
+		for _, code := range errorCodes(operation.Responses, successCode) {
+			if responseRef := operation.Responses[code]; responseRef != nil && responseRef.Value != nil {
+				data.ErrorResponses = append(data.ErrorResponses, c.responseInfo(code, responseRef.Value))
+			}
+		}
+	}
+	return data
+}
+
+// responseInfo describes a response by its status code, selected media
+// type, description and schema
+func (c *Converter) responseInfo(code string, response *openapi3.Response) responseInfo {
+	info := responseInfo{StatusCode: code}
+	if response.Description != nil {
+		info.Description = *response.Description
+	}
+	contentType, mediaType := selectResponseContent(response)
+	info.ContentType = contentType
+	if mediaType != nil && mediaType.Schema != nil && mediaType.Schema.Value != nil {
+		info.Schema = c.flattenSchema(mediaType.Schema.Value)
+	}
+	return info
+}
+
+// fieldTable renders the fields of a schema as a markdown table. Nested
+// fields are named by their path, e.g. address.city or items[].id.
+func (c *Converter) fieldTable(schema *openapi3.Schema) string {
+	if schema == nil {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("| Field | Type | Required | Description |\n")
+	b.WriteString("|-------|------|----------|-------------|\n")
+	c.writeFieldRows(&b, schema, "", 0)
+	return b.String()
+}
+
+// writeFieldRows writes a table row for each field of a schema, recursing
+// into nested objects and array items
+func (c *Converter) writeFieldRows(b *strings.Builder, schema *openapi3.Schema, path string, depth int, ancestors ...*openapi3.Schema) {
+	schema = c.flattenSchema(schema)
+	if depth > maxPropertyRecursionDepth || isRecursive(schema, ancestors) {
+		return
+	}
+	ancestors = append(ancestors, schema)
+
+	if schema.Type == "array" && schema.Items != nil && schema.Items.Value != nil {
+		c.writeFieldRows(b, schema.Items.Value, path+"[]", depth+1, ancestors...)
+		return
+	}
+
+	propNames := make([]string, 0, len(schema.Properties))
+	for propName := range schema.Properties {
+		propNames = append(propNames, propName)
+	}
+	sort.Strings(propNames)
+
+	for _, propName := range propNames {
+		propRef := schema.Properties[propName]
+		if propRef == nil || propRef.Value == nil {
+			continue
+		}
+		propSchema := c.flattenSchema(propRef.Value)
+		propPath := joinPropertyPath(path, propName)
+
+		required := "no"
+		if contains(schema.Required, propName) {
+			required = "yes"
+		}
+		b.WriteString(fmt.Sprintf("| `%s` | %s | %s | %s |\n", propPath, propSchema.Type, required, tableCell(propSchema.Description)))
+
+		c.writeFieldRows(b, propSchema, propPath, depth+1, ancestors...)
+	}
+}
+
+// tableCell escapes text for a markdown table cell
+func tableCell(text string) string {
+	text = strings.ReplaceAll(text, "|", "\\|")
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// jsonExample renders an example of a schema as indented JSON, using the
+// examples, defaults and enum values of the schema and placeholders of the
+// right type elsewhere
+func (c *Converter) jsonExample(schema *openapi3.Schema) (string, error) {
+	if schema == nil {
+		return "", nil
+	}
+	data, err := json.MarshalIndent(c.schemaExample(schema, 0), "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// schemaExample builds an example value of a schema
+func (c *Converter) schemaExample(schema *openapi3.Schema, depth int, ancestors ...*openapi3.Schema) interface{} {
+	schema = c.flattenSchema(schema)
+	if schema.Example != nil {
+		return schema.Example
+	}
+	if schema.Default != nil {
+		return schema.Default
+	}
+	if len(schema.Enum) > 0 {
+		return schema.Enum[0]
+	}
+	if depth > maxPropertyRecursionDepth || isRecursive(schema, ancestors) {
+		return nil
+	}
+	ancestors = append(ancestors, schema)
+
+	// A polymorphic schema is illustrated by its first alternative
+	if _, alternatives := schemaAlternatives(schema); len(alternatives) > 0 && len(schema.Properties) == 0 {
+		if alternatives[0] != nil && alternatives[0].Value != nil {
+			return c.schemaExample(alternatives[0].Value, depth+1, ancestors...)
+		}
+	}
+
+	switch schema.Type {
+	case "object":
+		example := map[string]interface{}{}
+		for propName, propRef := range schema.Properties {
+			if propRef != nil && propRef.Value != nil {
+				example[propName] = c.schemaExample(propRef.Value, depth+1, ancestors...)
+			}
+		}
+		return example
+	case "array":
+		if schema.Items == nil || schema.Items.Value == nil {
+			return []interface{}{}
+		}
+		return []interface{}{c.schemaExample(schema.Items.Value, depth+1, ancestors...)}
+	case "string":
+		if schema.Format != "" {
+			return schema.Format
+		}
+		return "string"
+	case "integer", "number":
+		return 0
+	case "boolean":
+		return false
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package converter
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// responseContentTypePreference is the order in which the media type of a
+// response is chosen: JSON first, as its schema describes the fields
+var responseContentTypePreference = []string{
+	"application/json",
+	"application/*+json",
+	"text/plain",
+}
+
+// successResponse selects the response of a successful call: 200, then the
+// other 2xx codes in ascending order, then the 2XX range, then default. It
+// returns the status code key of the response, or nil when there is none.
+func successResponse(operation *openapi3.Operation) (string, *openapi3.Response) {
+	if operation.Responses == nil {
+		return "", nil
+	}
+	for _, code := range successCodes(operation.Responses) {
+		if responseRef := operation.Responses[code]; responseRef != nil && responseRef.Value != nil {
+			return code, responseRef.Value
+		}
+	}
+	return "", nil
+}
+
+// successCodes lists the keys of the successful responses in the order they
+// are preferred
+func successCodes(responses openapi3.Responses) []string {
+	var codes, ranges, defaults []string
+	for key := range responses {
+		switch {
+		case strings.EqualFold(key, "2XX"):
+			ranges = append(ranges, key)
+		case key == "default":
+			defaults = append(defaults, key)
+		case len(key) == 3 && key[0] == '2':
+			if _, err := strconv.Atoi(key); err == nil {
+				codes = append(codes, key)
+			}
+		}
+	}
+	// Three digit codes sort numerically, so 200 comes first
+	sort.Strings(codes)
+	sort.Strings(ranges)
+	return append(append(codes, ranges...), defaults...)
+}
+
+// selectResponseContent chooses the media type of a response, preferring
+// JSON. It returns an empty content type when the response has no content.
+func selectResponseContent(response *openapi3.Response) (string, *openapi3.MediaType) {
+	if len(response.Content) == 0 {
+		return "", nil
+	}
+	selected, _ := selectContent(response.Content, responseContentTypePreference)
+	return selected, response.Content[selected]
+}
+
+// errorCodes lists the keys of the responses other than the successful ones:
+// explicit codes and ranges such as 4XX in ascending order, then default
+// unless it is the response of a successful call
+func errorCodes(responses openapi3.Responses, successCode string) []string {
+	var codes []string
+	hasDefault := false
+	for key := range responses {
+		switch {
+		case key == "default":
+			hasDefault = key != successCode
+		case strings.HasPrefix(key, "2"):
+		default:
+			codes = append(codes, key)
+		}
+	}
+	// Ranges such as 4XX sort after the codes they cover
+	sort.Strings(codes)
+	if hasDefault {
+		codes = append(codes, "default")
+	}
+	return codes
+}
+
+// describeErrorResponses returns the "Error Responses" section of the
+// response description: the description and the fields of each response
+// other than the successful one, or an empty string when there are none
+func (c *Converter) describeErrorResponses(operation *openapi3.Operation) string {
+	if operation.Responses == nil {
+		return ""
+	}
+	successCode, _ := successResponse(operation)
+
+	var b strings.Builder
+	for _, code := range errorCodes(operation.Responses, successCode) {
+		responseRef := operation.Responses[code]
+		if responseRef == nil || responseRef.Value == nil {
+			continue
+		}
+		response := responseRef.Value
+
+		b.WriteString("### " + code)
+		if status, err := strconv.Atoi(code); err == nil && http.StatusText(status) != "" {
+			b.WriteString(" " + http.StatusText(status))
+		}
+		b.WriteString("\n\n")
+		if response.Description != nil && *response.Description != "" {
+			b.WriteString(*response.Description + "\n\n")
+		}
+		contentType, mediaType := selectResponseContent(response)
+		if mediaType != nil && mediaType.Schema != nil && mediaType.Schema.Value != nil {
+			b.WriteString(fmt.Sprintf("> Content-Type: %s\n\n", contentType))
+			c.describeSchemaFields(&b, mediaType.Schema.Value)
+			b.WriteString("\n")
+		}
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return "\n## Error Responses\n\n" + strings.TrimRight(b.String(), "\n") + "\n"
+}
@@ -0,0 +1,623 @@
+package converter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/higress-group/openapi-to-mcpserver/internal/models"
+)
+
+// componentSchemaPrefix is the reference prefix of component schemas
+const componentSchemaPrefix = "#/components/schemas/"
+
+// flattenSchema returns the effective schema of a composition: the members
+// of allOf are merged into a single property set. oneOf/anyOf are kept as
+// alternatives. Results are cached so that the same input always maps to the
+// same pointer, which keeps recursion detection working on merged schemas.
+func (c *Converter) flattenSchema(schema *openapi3.Schema) *openapi3.Schema {
+	if schema == nil || len(schema.AllOf) == 0 {
+		return schema
+	}
+	if merged, ok := c.flattened[schema]; ok {
+		return merged
+	}
+
+	merged := &openapi3.Schema{}
+	*merged = *schema
+	merged.AllOf = nil
+	merged.Properties = make(openapi3.Schemas, len(schema.Properties))
+	for name, prop := range schema.Properties {
+		merged.Properties[name] = prop
+	}
+	merged.Required = append([]string(nil), schema.Required...)
+	merged.OneOf = append(openapi3.SchemaRefs(nil), schema.OneOf...)
+	merged.AnyOf = append(openapi3.SchemaRefs(nil), schema.AnyOf...)
+
+	// Register before merging so a member referring back to schema ends the recursion
+	c.flattened[schema] = merged
+
+	for _, memberRef := range schema.AllOf {
+		if memberRef == nil || memberRef.Value == nil {
+			continue
+		}
+		mergeSchema(merged, c.flattenSchema(memberRef.Value))
+	}
+
+	if merged.Type == "" && len(merged.Properties) > 0 {
+		merged.Type = "object"
+	}
+	return merged
+}
+
+// mergeSchema merges an allOf member into target. Values already set on the
+// target win, properties and required names are combined. Every member
+// applies, so readOnly/writeOnly set by any member hold and bounds set by
+// several members take the stricter one.
+func mergeSchema(target, member *openapi3.Schema) {
+	if target.Type == "" {
+		target.Type = member.Type
+	}
+	if target.Title == "" {
+		target.Title = member.Title
+	}
+	if target.Description == "" {
+		target.Description = member.Description
+	}
+	if target.Format == "" {
+		target.Format = member.Format
+	}
+	if len(target.Enum) == 0 {
+		target.Enum = member.Enum
+	}
+	if target.Default == nil {
+		target.Default = member.Default
+	}
+	if target.Example == nil {
+		target.Example = member.Example
+	}
+	if target.Items == nil {
+		target.Items = member.Items
+	}
+	if target.Discriminator == nil {
+		target.Discriminator = member.Discriminator
+	}
+	target.Nullable = target.Nullable || member.Nullable
+	target.ReadOnly = target.ReadOnly || member.ReadOnly
+	target.WriteOnly = target.WriteOnly || member.WriteOnly
+	target.UniqueItems = target.UniqueItems || member.UniqueItems
+
+	target.Min, target.ExclusiveMin = stricterBound(target.Min, target.ExclusiveMin, member.Min, member.ExclusiveMin, 1)
+	target.Max, target.ExclusiveMax = stricterBound(target.Max, target.ExclusiveMax, member.Max, member.ExclusiveMax, -1)
+	if target.MultipleOf == nil {
+		target.MultipleOf = member.MultipleOf
+	}
+	if member.MinLength > target.MinLength {
+		target.MinLength = member.MinLength
+	}
+	target.MaxLength = smallerLimit(target.MaxLength, member.MaxLength)
+	if target.Pattern == "" {
+		target.Pattern = member.Pattern
+	}
+	if member.MinItems > target.MinItems {
+		target.MinItems = member.MinItems
+	}
+	target.MaxItems = smallerLimit(target.MaxItems, member.MaxItems)
+	if member.MinProps > target.MinProps {
+		target.MinProps = member.MinProps
+	}
+	target.MaxProps = smallerLimit(target.MaxProps, member.MaxProps)
+
+	// additionalProperties: false forbids extra properties whatever the
+	// other members allow
+	memberAdditional := member.AdditionalProperties
+	switch {
+	case memberAdditional.Has != nil && !*memberAdditional.Has:
+		target.AdditionalProperties = memberAdditional
+	case target.AdditionalProperties.Has == nil && target.AdditionalProperties.Schema == nil:
+		target.AdditionalProperties = memberAdditional
+	}
+
+	for name, prop := range member.Properties {
+		if _, exists := target.Properties[name]; !exists {
+			target.Properties[name] = prop
+		}
+	}
+	for _, name := range member.Required {
+		if !contains(target.Required, name) {
+			target.Required = append(target.Required, name)
+		}
+	}
+	target.OneOf = append(target.OneOf, member.OneOf...)
+	target.AnyOf = append(target.AnyOf, member.AnyOf...)
+}
+
+// stricterBound combines the numeric bounds of two allOf members. sign is 1
+// for minimums, where the larger bound is stricter, and -1 for maximums. At
+// equal values an exclusive bound is stricter than an inclusive one.
+func stricterBound(bound *float64, exclusive bool, other *float64, otherExclusive bool, sign float64) (*float64, bool) {
+	switch {
+	case other == nil:
+		return bound, exclusive
+	case bound == nil:
+		return other, otherExclusive
+	case *other*sign > *bound*sign:
+		return other, otherExclusive
+	case *other == *bound:
+		return bound, exclusive || otherExclusive
+	default:
+		return bound, exclusive
+	}
+}
+
+// smallerLimit returns the stricter of two optional upper limits
+func smallerLimit(limit, other *uint64) *uint64 {
+	if limit == nil || (other != nil && *other < *limit) {
+		return other
+	}
+	return limit
+}
+
+// schemaAlternatives returns the oneOf or anyOf alternatives of a schema,
+// together with the keyword they were declared with
+func schemaAlternatives(schema *openapi3.Schema) (string, openapi3.SchemaRefs) {
+	if len(schema.OneOf) > 0 {
+		return "oneOf", schema.OneOf
+	}
+	if len(schema.AnyOf) > 0 {
+		return "anyOf", schema.AnyOf
+	}
+	return "", nil
+}
+
+// applySchemaToArg fills the schema derived fields of an argument: type,
+// nullability, enum values, array items, object properties and, for
+// polymorphic schemas, the alternatives and discriminator
+func (c *Converter) applySchemaToArg(arg *models.Arg, schema *openapi3.Schema, ancestors ...*openapi3.Schema) error {
+	schema = c.flattenSchema(schema)
+
+	// Set the type based on the schema type
+	arg.Type = schema.Type
+	arg.Nullable = schema.Nullable
+	if arg.Description == "" {
+		arg.Description = schema.Description
+	}
+	applySchemaConstraints(arg, schema)
+
+	// Handle enum values
+	if len(schema.Enum) > 0 {
+		arg.Enum = schema.Enum
+	}
+
+	// Handle array type
+	if schema.Type == "array" && schema.Items != nil && schema.Items.Value != nil {
+		items, err := c.itemsInfo(schema.Items.Value, 1, append(ancestors, schema)...)
+		if err != nil {
+			return err
+		}
+		arg.Items = items
+	}
+
+	// Handle object type
+	if schema.Type == "object" && len(schema.Properties) > 0 && !isRecursive(schema, ancestors) {
+		properties, err := c.convertSchemaToProperties(schema, 1, ancestors...)
+		if err != nil {
+			return err
+		}
+		if properties != nil {
+			arg.Properties = properties
+		}
+	}
+	if schema.Type == "object" && !isRecursive(schema, ancestors) {
+		arg.RequiredProperties = c.requestRequired(schema)
+		additional, err := c.additionalProperties(schema, 1, append(ancestors, schema)...)
+		if err != nil {
+			return err
+		}
+		arg.AdditionalProperties = additional
+	}
+
+	// Handle oneOf/anyOf alternatives
+	keyword, _ := schemaAlternatives(schema)
+	if keyword == "" {
+		return nil
+	}
+	alternatives, err := c.convertAlternatives(schema, 1, ancestors...)
+	if err != nil {
+		return err
+	}
+	if keyword == "oneOf" {
+		arg.OneOf = alternatives
+	} else {
+		arg.AnyOf = alternatives
+	}
+	arg.Discriminator = discriminatorInfo(schema)
+	if arg.Type == "" {
+		arg.Type = c.commonType(schema)
+	}
+	return nil
+}
+
+// applySchemaConstraints copies the format, value constraints, default and
+// example of a schema onto an argument. Values already set on the argument,
+// such as a parameter level example, are kept.
+func applySchemaConstraints(arg *models.Arg, schema *openapi3.Schema) {
+	arg.Format = schema.Format
+	arg.Minimum = schema.Min
+	arg.Maximum = schema.Max
+	arg.ExclusiveMinimum = schema.ExclusiveMin
+	arg.ExclusiveMaximum = schema.ExclusiveMax
+	arg.MultipleOf = schema.MultipleOf
+	arg.MinLength = schema.MinLength
+	arg.MaxLength = schema.MaxLength
+	arg.Pattern = schema.Pattern
+	arg.MinItems = schema.MinItems
+	arg.MaxItems = schema.MaxItems
+	arg.UniqueItems = schema.UniqueItems
+	arg.WriteOnly = schema.WriteOnly
+	if arg.Default == nil {
+		arg.Default = schema.Default
+	}
+	if arg.Example == nil {
+		arg.Example = schema.Example
+	}
+}
+
+// schemaConstraints returns the format, value constraints, default and
+// example of a schema, keyed by their JSON Schema names, for use in property
+// and items maps
+func schemaConstraints(schema *openapi3.Schema) map[string]interface{} {
+	constraints := map[string]interface{}{}
+	if schema.Format != "" {
+		constraints["format"] = schema.Format
+	}
+	if schema.Min != nil {
+		constraints["minimum"] = *schema.Min
+	}
+	if schema.Max != nil {
+		constraints["maximum"] = *schema.Max
+	}
+	if schema.ExclusiveMin {
+		constraints["exclusiveMinimum"] = true
+	}
+	if schema.ExclusiveMax {
+		constraints["exclusiveMaximum"] = true
+	}
+	if schema.MultipleOf != nil {
+		constraints["multipleOf"] = *schema.MultipleOf
+	}
+	if schema.MinLength > 0 {
+		constraints["minLength"] = schema.MinLength
+	}
+	if schema.MaxLength != nil {
+		constraints["maxLength"] = *schema.MaxLength
+	}
+	if schema.Pattern != "" {
+		constraints["pattern"] = schema.Pattern
+	}
+	if schema.MinItems > 0 {
+		constraints["minItems"] = schema.MinItems
+	}
+	if schema.MaxItems != nil {
+		constraints["maxItems"] = *schema.MaxItems
+	}
+	if schema.UniqueItems {
+		constraints["uniqueItems"] = true
+	}
+	if schema.WriteOnly {
+		constraints["writeOnly"] = true
+	}
+	if schema.Default != nil {
+		constraints["default"] = schema.Default
+	}
+	if schema.Example != nil {
+		constraints["example"] = schema.Example
+	}
+	return constraints
+}
+
+// convertAlternatives converts the oneOf/anyOf alternatives of a schema into
+// property style maps. Alternatives defined as component schemas are named
+// after the component through the title field.
+func (c *Converter) convertAlternatives(schema *openapi3.Schema, depth int, ancestors ...*openapi3.Schema) ([]map[string]interface{}, error) {
+	_, alternativeRefs := schemaAlternatives(schema)
+	ancestors = append(ancestors, schema)
+
+	alternatives := make([]map[string]interface{}, 0, len(alternativeRefs))
+	for _, altRef := range alternativeRefs {
+		if altRef == nil || altRef.Value == nil {
+			continue
+		}
+		altSchema := c.flattenSchema(altRef.Value)
+
+		altInfo := map[string]interface{}{
+			"type": altSchema.Type,
+		}
+		if title := schemaTitle(altRef, altSchema); title != "" {
+			altInfo["title"] = title
+		}
+		if altSchema.Description != "" {
+			altInfo["description"] = altSchema.Description
+		}
+		if len(altSchema.Enum) > 0 {
+			altInfo["enum"] = altSchema.Enum
+		}
+		if altSchema.Type == "object" && len(altSchema.Properties) > 0 && !isRecursive(altSchema, ancestors) {
+			properties, err := c.convertSchemaToProperties(altSchema, depth+1, ancestors...)
+			if err != nil {
+				return nil, fmt.Errorf("处理组合类型属性失败: %w", err)
+			}
+			if properties != nil {
+				altInfo["properties"] = properties
+			}
+		}
+		alternatives = append(alternatives, altInfo)
+	}
+	return alternatives, nil
+}
+
+// alternativesToInterfaces converts alternatives for use inside a property map
+func alternativesToInterfaces(alternatives []map[string]interface{}) []interface{} {
+	values := make([]interface{}, len(alternatives))
+	for i, alternative := range alternatives {
+		values[i] = alternative
+	}
+	return values
+}
+
+// discriminatorInfo describes the discriminator of a polymorphic schema. When
+// the spec gives no explicit mapping, the implicit one (component name to
+// component name) is derived from the alternatives.
+func discriminatorInfo(schema *openapi3.Schema) map[string]interface{} {
+	if schema.Discriminator == nil || schema.Discriminator.PropertyName == "" {
+		return nil
+	}
+
+	mapping := discriminatorMapping(schema)
+	info := map[string]interface{}{
+		"propertyName": schema.Discriminator.PropertyName,
+	}
+	if len(mapping) > 0 {
+		mappingInfo := make(map[string]interface{}, len(mapping))
+		for value, target := range mapping {
+			mappingInfo[value] = target
+		}
+		info["mapping"] = mappingInfo
+	}
+	return info
+}
+
+// discriminatorMapping returns the discriminator value to schema name mapping
+func discriminatorMapping(schema *openapi3.Schema) map[string]string {
+	if schema.Discriminator == nil {
+		return nil
+	}
+
+	mapping := make(map[string]string)
+	for value, ref := range schema.Discriminator.Mapping {
+		mapping[value] = strings.TrimPrefix(ref, componentSchemaPrefix)
+	}
+	if len(mapping) > 0 {
+		return mapping
+	}
+
+	_, alternatives := schemaAlternatives(schema)
+	for _, altRef := range alternatives {
+		if altRef != nil && strings.HasPrefix(altRef.Ref, componentSchemaPrefix) {
+			name := strings.TrimPrefix(altRef.Ref, componentSchemaPrefix)
+			mapping[name] = name
+		}
+	}
+	return mapping
+}
+
+// discriminatorValues returns the discriminator values selecting altRef
+func discriminatorValues(schema *openapi3.Schema, altRef *openapi3.SchemaRef) []string {
+	name := strings.TrimPrefix(altRef.Ref, componentSchemaPrefix)
+	if name == "" || name == altRef.Ref {
+		return nil
+	}
+
+	var values []string
+	for value, target := range discriminatorMapping(schema) {
+		if target == name {
+			values = append(values, value)
+		}
+	}
+	sort.Strings(values)
+	return values
+}
+
+// schemaTitle names a schema by its title or, failing that, by the component
+// it references
+func schemaTitle(schemaRef *openapi3.SchemaRef, schema *openapi3.Schema) string {
+	if schema.Title != "" {
+		return schema.Title
+	}
+	if strings.HasPrefix(schemaRef.Ref, componentSchemaPrefix) {
+		return strings.TrimPrefix(schemaRef.Ref, componentSchemaPrefix)
+	}
+	return ""
+}
+
+// commonType returns the type shared by all alternatives of a schema, or an
+// empty string when they differ
+func (c *Converter) commonType(schema *openapi3.Schema) string {
+	_, alternatives := schemaAlternatives(schema)
+	typ := ""
+	for _, altRef := range alternatives {
+		if altRef == nil || altRef.Value == nil {
+			continue
+		}
+		altType := c.flattenSchema(altRef.Value).Type
+		if altType == "" && len(altRef.Value.Properties) > 0 {
+			altType = "object"
+		}
+		if typ != "" && altType != typ {
+			return ""
+		}
+		typ = altType
+	}
+	return typ
+}
+
+// bodyProperties returns the properties of a request body schema that become
+// body arguments. For a polymorphic body the properties of all alternatives
+// are offered, and a property is required only when every alternative
+// requires it. owners maps each property to the schema declaring it.
+func (c *Converter) bodyProperties(schema *openapi3.Schema) (openapi3.Schemas, []string, map[string]*openapi3.Schema) {
+	properties := openapi3.Schemas{}
+	owners := map[string]*openapi3.Schema{}
+	var required []string
+
+	if schema.Type != "object" && schema.Type != "" {
+		return properties, required, owners
+	}
+
+	for name, prop := range schema.Properties {
+		properties[name] = prop
+		owners[name] = schema
+	}
+	required = append(required, schema.Required...)
+
+	_, alternatives := schemaAlternatives(schema)
+	requiredCount := map[string]int{}
+	objectAlternatives := 0
+	for _, altRef := range alternatives {
+		if altRef == nil || altRef.Value == nil {
+			continue
+		}
+		altSchema := c.flattenSchema(altRef.Value)
+		if len(altSchema.Properties) == 0 {
+			continue
+		}
+		objectAlternatives++
+		for name, prop := range altSchema.Properties {
+			if _, exists := properties[name]; !exists {
+				properties[name] = prop
+				owners[name] = altSchema
+			}
+		}
+		for _, name := range altSchema.Required {
+			requiredCount[name]++
+		}
+	}
+	for name, count := range requiredCount {
+		if count == objectAlternatives && !contains(required, name) {
+			required = append(required, name)
+		}
+	}
+
+	return properties, required, owners
+}
+
+// sortedMapKeys returns the keys of m in ascending order
+func sortedMapKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// requestRequired returns the required properties of an object schema that
+// are sent in requests, leaving out the read-only ones
+func (c *Converter) requestRequired(schema *openapi3.Schema) []string {
+	var required []string
+	for _, name := range schema.Required {
+		if prop := schema.Properties[name]; prop != nil && prop.Value != nil && c.flattenSchema(prop.Value).ReadOnly {
+			continue
+		}
+		required = append(required, name)
+	}
+	return required
+}
+
+// additionalProperties returns the additionalProperties keyword of an object
+// schema for request arguments: false when no other properties are allowed,
+// the property map of the accepted values, or nil when they are unrestricted
+func (c *Converter) additionalProperties(schema *openapi3.Schema, depth int, ancestors ...*openapi3.Schema) (interface{}, error) {
+	additional := schema.AdditionalProperties
+	if additional.Has != nil && !*additional.Has {
+		return false, nil
+	}
+	if additional.Schema == nil || additional.Schema.Value == nil {
+		return nil, nil
+	}
+
+	valueSchema := c.flattenSchema(additional.Schema.Value)
+	info := map[string]interface{}{
+		"type": valueSchema.Type,
+	}
+	if valueSchema.Description != "" {
+		info["description"] = valueSchema.Description
+	}
+	if len(valueSchema.Enum) > 0 {
+		info["enum"] = valueSchema.Enum
+	}
+	for keyword, value := range schemaConstraints(valueSchema) {
+		info[keyword] = value
+	}
+	if valueSchema.Type == "object" && !isRecursive(valueSchema, ancestors) {
+		properties, err := c.convertSchemaToProperties(valueSchema, depth+1, ancestors...)
+		if err != nil {
+			return nil, err
+		}
+		if properties != nil {
+			info["properties"] = properties
+		}
+		if err := c.addObjectKeywords(info, valueSchema, depth+1, ancestors...); err != nil {
+			return nil, err
+		}
+	}
+	return info, nil
+}
+
+// itemsInfo converts the items schema of an array into a property style map.
+// Object items keep their properties, required names and additional
+// properties, so that callers know the shape of each element.
+func (c *Converter) itemsInfo(itemSchema *openapi3.Schema, depth int, ancestors ...*openapi3.Schema) (map[string]interface{}, error) {
+	itemSchema = c.flattenSchema(itemSchema)
+	info := map[string]interface{}{
+		"type": itemSchema.Type,
+	}
+	if len(itemSchema.Enum) > 0 {
+		info["enum"] = itemSchema.Enum
+	}
+	for keyword, value := range schemaConstraints(itemSchema) {
+		info[keyword] = value
+	}
+
+	// 如果数组项是对象，递归处理其属性
+	if itemSchema.Type == "object" && !isRecursive(itemSchema, ancestors) {
+		properties, err := c.convertSchemaToProperties(itemSchema, depth, ancestors...)
+		if err != nil {
+			return nil, fmt.Errorf("处理数组项属性失败: %w", err)
+		}
+		if properties != nil {
+			info["properties"] = properties
+		}
+		if err := c.addObjectKeywords(info, itemSchema, depth, ancestors...); err != nil {
+			return nil, err
+		}
+	}
+	return info, nil
+}
+
+// addObjectKeywords adds the required and additionalProperties keywords of
+// an object schema to its property map
+func (c *Converter) addObjectKeywords(info map[string]interface{}, schema *openapi3.Schema, depth int, ancestors ...*openapi3.Schema) error {
+	if required := c.requestRequired(schema); len(required) > 0 {
+		info["required"] = required
+	}
+	additional, err := c.additionalProperties(schema, depth, append(ancestors, schema)...)
+	if err != nil {
+		return err
+	}
+	if additional != nil {
+		info["additionalProperties"] = additional
+	}
+	return nil
+}
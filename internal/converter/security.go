@@ -0,0 +1,262 @@
+package converter
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/higress-group/openapi-to-mcpserver/internal/models"
+	"github.com/higress-group/openapi-to-mcpserver/internal/naming"
+)
+
+// securityFields lists the server config fields a security scheme needs,
+// in the order they are written to the configuration
+func securityFields(scheme *openapi3.SecurityScheme) []string {
+	switch strings.ToLower(scheme.Type) {
+	case "apikey":
+		return []string{"apiKey"}
+	case "http":
+		switch strings.ToLower(scheme.Scheme) {
+		case "basic":
+			return []string{"username", "password"}
+		case "bearer":
+			return []string{"token"}
+		}
+	case "oauth2", "openidconnect":
+		// Neither Higress nor the built-in runtime obtains tokens, so OAuth2
+		// flows need a token issued beforehand
+		return []string{"token"}
+	}
+	return nil
+}
+
+// securityConfigKeys maps every supported security scheme of the document to
+// the server config keys of its fields. A field name shared by several
+// schemes is prefixed with the scheme name, e.g. petstoreAuthToken.
+func (c *Converter) securityConfigKeys() map[string]map[string]string {
+	schemes := c.securitySchemes()
+	names := make([]string, 0, len(schemes))
+	usage := map[string]int{}
+	for name, scheme := range schemes {
+		names = append(names, name)
+		for _, field := range securityFields(scheme) {
+			usage[field]++
+		}
+	}
+	sort.Strings(names)
+
+	keys := make(map[string]map[string]string, len(names))
+	for _, name := range names {
+		fields := securityFields(schemes[name])
+		if len(fields) == 0 {
+			continue
+		}
+		keys[name] = make(map[string]string, len(fields))
+		for _, field := range fields {
+			key := field
+			if usage[field] > 1 {
+				// Avoid repeating the field when the scheme is already named after it
+				key = naming.Join(naming.CasingCamel, name)
+				if !strings.Contains(strings.ToLower(key), strings.ToLower(field)) {
+					key = naming.Join(naming.CasingCamel, name, field)
+				}
+			}
+			keys[name][field] = key
+		}
+	}
+	return keys
+}
+
+// securitySchemes returns the resolved security schemes of the document
+func (c *Converter) securitySchemes() map[string]*openapi3.SecurityScheme {
+	schemes := map[string]*openapi3.SecurityScheme{}
+	doc := c.parser.GetDocument()
+	if doc == nil || doc.Components == nil {
+		return schemes
+	}
+	for name, schemeRef := range doc.Components.SecuritySchemes {
+		if schemeRef != nil && schemeRef.Value != nil {
+			schemes[name] = schemeRef.Value
+		}
+	}
+	return schemes
+}
+
+// operationSecurity returns the names of the security schemes applied to an
+// operation, together with the requirement they come from. Operation-level
+// security overrides the document-level one, and an empty list opts out of
+// authentication. Of several alternative requirements the first one whose
+// schemes are all supported is used.
+func (c *Converter) operationSecurity(operation *openapi3.Operation) ([]string, openapi3.SecurityRequirement) {
+	requirements := c.parser.GetDocument().Security
+	if operation.Security != nil {
+		requirements = *operation.Security
+	}
+
+	for _, requirement := range requirements {
+		if len(requirement) == 0 {
+			continue
+		}
+		names := make([]string, 0, len(requirement))
+		supported := true
+		for name := range requirement {
+			if _, ok := c.securityKeys[name]; !ok {
+				supported = false
+				break
+			}
+			names = append(names, name)
+		}
+		if supported {
+			sort.Strings(names)
+			return names, requirement
+		}
+	}
+	return nil, nil
+}
+
+// warnSecurity reports what the configuration cannot express about an
+// operation's security: the OAuth2 scopes the configured token has to grant,
+// since no token is requested at runtime, and requirements whose schemes are
+// all unsupported, which leave the tool without credentials
+func (c *Converter) warnSecurity(toolName string, operation *openapi3.Operation) {
+	names, requirement := c.operationSecurity(operation)
+	for _, name := range names {
+		if scopes := requirement[name]; len(scopes) > 0 {
+			c.warnings = append(c.warnings, fmt.Sprintf("%s: the token of %s must grant the scopes %s", toolName, name, strings.Join(scopes, " ")))
+		}
+	}
+	if len(names) > 0 {
+		return
+	}
+
+	requirements := c.parser.GetDocument().Security
+	if operation.Security != nil {
+		requirements = *operation.Security
+	}
+	var unsupported []string
+	for _, requirement := range requirements {
+		if len(requirement) == 0 {
+			// Authentication is optional
+			return
+		}
+		for name := range requirement {
+			if _, ok := c.securityKeys[name]; !ok && !contains(unsupported, name) {
+				unsupported = append(unsupported, name)
+			}
+		}
+	}
+	if len(unsupported) > 0 {
+		sort.Strings(unsupported)
+		schemes := c.securitySchemes()
+		for i, name := range unsupported {
+			unsupported[i] = fmt.Sprintf("%s (%s)", name, describeScheme(schemes[name]))
+		}
+		c.warnings = append(c.warnings, fmt.Sprintf("%s: no supported security requirement, the API is called without credentials (unsupported: %s)", toolName, strings.Join(unsupported, ", ")))
+	}
+}
+
+// describeScheme names the type of a security scheme in warnings
+func describeScheme(scheme *openapi3.SecurityScheme) string {
+	switch {
+	case scheme == nil:
+		return "undefined"
+	case scheme.Scheme != "":
+		return scheme.Type + " " + scheme.Scheme
+	case scheme.In != "":
+		return scheme.Type + " in " + scheme.In
+	}
+	return scheme.Type
+}
+
+// warnClientCredentials reports an OAuth2 client credentials flow, whose
+// token has to be obtained from the token URL before it is configured
+func (c *Converter) warnClientCredentials(name string, scheme *openapi3.SecurityScheme, keys map[string]string) {
+	if scheme.Flows == nil || scheme.Flows.ClientCredentials == nil {
+		return
+	}
+	c.warnings = append(c.warnings, fmt.Sprintf("the OAuth2 client credentials flow of %s is not performed, set %s to an access token issued by %s", name, keys["token"], scheme.Flows.ClientCredentials.TokenURL))
+}
+
+// applySecurity adds the credentials of the operation's security schemes to
+// a request template: headers, cookies and query parameters referring to
+// server config placeholders. The placeholders are collected in
+// c.securityConfig.
+func (c *Converter) applySecurity(template *models.RequestTemplate, operation *openapi3.Operation) {
+	schemes := c.securitySchemes()
+
+	var cookies, queryParams []string
+	names, _ := c.operationSecurity(operation)
+	for _, name := range names {
+		scheme := schemes[name]
+		keys := c.securityKeys[name]
+		if c.addSecurityConfig(scheme, keys) {
+			c.warnClientCredentials(name, scheme, keys)
+		}
+
+		switch strings.ToLower(scheme.Type) {
+		case "apikey":
+			value := "{{.config." + keys["apiKey"] + "}}"
+			switch strings.ToLower(scheme.In) {
+			case "header":
+				template.Headers = append(template.Headers, models.Header{Key: scheme.Name, Value: value})
+			case "query":
+				queryParams = append(queryParams, url.QueryEscape(scheme.Name)+"={{urlquery .config."+keys["apiKey"]+"}}")
+			case "cookie":
+				cookies = append(cookies, scheme.Name+"="+value)
+			}
+		case "http":
+			if strings.ToLower(scheme.Scheme) == "basic" {
+				template.Headers = append(template.Headers, models.Header{
+					Key:   "Authorization",
+					Value: `Basic {{b64enc (printf "%s:%s" .config.` + keys["username"] + ` .config.` + keys["password"] + `)}}`,
+				})
+				continue
+			}
+			template.Headers = append(template.Headers, models.Header{Key: "Authorization", Value: "Bearer {{.config." + keys["token"] + "}}"})
+		default:
+			template.Headers = append(template.Headers, models.Header{Key: "Authorization", Value: "Bearer {{.config." + keys["token"] + "}}"})
+		}
+	}
+
+	if len(cookies) > 0 {
+		template.Headers = append(template.Headers, models.Header{Key: "Cookie", Value: strings.Join(cookies, "; ")})
+	}
+	if len(queryParams) > 0 {
+		separator := "?"
+		if strings.Contains(template.URL, "?") {
+			separator = "&"
+		}
+		template.URL += separator + strings.Join(queryParams, "&")
+	}
+}
+
+// addSecurityConfig records the empty config placeholders of a scheme and
+// reports whether they were added for the first time
+func (c *Converter) addSecurityConfig(scheme *openapi3.SecurityScheme, keys map[string]string) bool {
+	added := false
+	for _, field := range securityFields(scheme) {
+		if _, exists := c.securityConfig[keys[field]]; !exists {
+			c.securityConfig[keys[field]] = ""
+			added = true
+		}
+	}
+	return added
+}
+
+// mergeSecurityConfig adds the collected security placeholders to a server
+// config, keeping the values the user already provided
+func (c *Converter) mergeSecurityConfig(config map[string]interface{}) map[string]interface{} {
+	if len(c.securityConfig) == 0 {
+		return config
+	}
+	merged := make(map[string]interface{}, len(config)+len(c.securityConfig))
+	for key, value := range c.securityConfig {
+		merged[key] = value
+	}
+	for key, value := range config {
+		merged[key] = value
+	}
+	return merged
+}
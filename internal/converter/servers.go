@@ -0,0 +1,176 @@
+package converter
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// serverVariablePattern matches {variable} placeholders in server URLs
+var serverVariablePattern = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// validateServerSelection validates the server selection options up front,
+// so that a bad selection is reported once. A ServerDescription may match a
+// document-level server or a path- or operation-level override, while
+// ServerIndex applies to the document-level servers.
+func (c *Converter) validateServerSelection() error {
+	if c.options.ServerDescription != "" {
+		lists := c.serverLists()
+		matched := len(lists) == 0
+		for _, servers := range lists {
+			if matchServerByDescription(servers, c.options.ServerDescription) != nil {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("no server matches description %q", c.options.ServerDescription)
+		}
+	}
+
+	servers := c.parser.GetServers()
+	if len(servers) > 0 && (c.options.ServerIndex < 0 || c.options.ServerIndex >= len(servers)) {
+		return fmt.Errorf("server index %d out of range, the document defines %d server(s)", c.options.ServerIndex, len(servers))
+	}
+	return nil
+}
+
+// serverLists returns the non-empty server lists of the document, its paths
+// and its operations
+func (c *Converter) serverLists() []openapi3.Servers {
+	var lists []openapi3.Servers
+	if servers := c.parser.GetServers(); len(servers) > 0 {
+		lists = append(lists, servers)
+	}
+	for _, pathItem := range c.parser.GetPaths() {
+		if pathItem == nil {
+			continue
+		}
+		if len(pathItem.Servers) > 0 {
+			lists = append(lists, pathItem.Servers)
+		}
+		for _, operation := range pathItem.Operations() {
+			if operation.Servers != nil && len(*operation.Servers) > 0 {
+				lists = append(lists, *operation.Servers)
+			}
+		}
+	}
+	return lists
+}
+
+// serverURL returns the base URL of an operation. Operation-level servers
+// override path-level ones, which override the document-level servers.
+// Server variables are substituted and relative URLs are resolved against
+// the configured base URL.
+func (c *Converter) serverURL(pathItem *openapi3.PathItem, operation *openapi3.Operation) (string, error) {
+	servers := c.parser.GetServers()
+	if pathItem != nil && len(pathItem.Servers) > 0 {
+		servers = pathItem.Servers
+	}
+	if operation.Servers != nil && len(*operation.Servers) > 0 {
+		servers = *operation.Servers
+	}
+
+	server := c.selectServer(servers)
+	if server == nil {
+		return c.resolveRelativeURL("")
+	}
+
+	serverURL, err := c.expandServerVariables(server)
+	if err != nil {
+		return "", err
+	}
+	return c.resolveRelativeURL(serverURL)
+}
+
+// selectServer picks a server from a list: the first whose description
+// matches ServerDescription, else the one at ServerIndex, else the first
+func (c *Converter) selectServer(servers openapi3.Servers) *openapi3.Server {
+	if len(servers) == 0 {
+		return nil
+	}
+	if c.options.ServerDescription != "" {
+		if server := matchServerByDescription(servers, c.options.ServerDescription); server != nil {
+			return server
+		}
+	}
+	if c.options.ServerIndex > 0 && c.options.ServerIndex < len(servers) {
+		return servers[c.options.ServerIndex]
+	}
+	return servers[0]
+}
+
+// matchServerByDescription returns the server whose description equals
+// description, ignoring case, or failing that the first one containing it
+func matchServerByDescription(servers openapi3.Servers, description string) *openapi3.Server {
+	description = strings.ToLower(description)
+	for _, server := range servers {
+		if server != nil && strings.ToLower(server.Description) == description {
+			return server
+		}
+	}
+	for _, server := range servers {
+		if server != nil && strings.Contains(strings.ToLower(server.Description), description) {
+			return server
+		}
+	}
+	return nil
+}
+
+// expandServerVariables substitutes the {variable} placeholders of a server
+// URL with the ServerVariables option or the variable's default value
+func (c *Converter) expandServerVariables(server *openapi3.Server) (string, error) {
+	var expandErr error
+	expanded := serverVariablePattern.ReplaceAllStringFunc(server.URL, func(placeholder string) string {
+		name := strings.Trim(placeholder, "{}")
+		variable := server.Variables[name]
+
+		value, overridden := c.options.ServerVariables[name]
+		if !overridden {
+			if variable == nil {
+				if expandErr == nil {
+					expandErr = invalidOptions(fmt.Errorf("server variable %q in %q has no value", name, server.URL))
+				}
+				return placeholder
+			}
+			value = variable.Default
+		}
+
+		if overridden && variable != nil && len(variable.Enum) > 0 && !contains(variable.Enum, value) {
+			if expandErr == nil {
+				expandErr = invalidOptions(fmt.Errorf("value %q of server variable %q is not one of %s", value, name, strings.Join(variable.Enum, ", ")))
+			}
+		}
+		return value
+	})
+	return expanded, expandErr
+}
+
+// resolveRelativeURL resolves a relative server URL against the BaseURL
+// option. Absolute URLs, and relative ones without a base URL, are kept.
+func (c *Converter) resolveRelativeURL(serverURL string) (string, error) {
+	if c.options.BaseURL == "" {
+		return serverURL, nil
+	}
+
+	parsed, err := url.Parse(serverURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid server URL %q: %w", serverURL, err)
+	}
+	if parsed.IsAbs() {
+		return serverURL, nil
+	}
+
+	base, err := url.Parse(c.options.BaseURL)
+	if err != nil {
+		return "", invalidOptions(fmt.Errorf("invalid base URL %q: %w", c.options.BaseURL, err))
+	}
+	// Treat the base URL as a directory so that relative paths are appended to it
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+	return base.ResolveReference(parsed).String(), nil
+}
@@ -0,0 +1,125 @@
+package converter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/higress-group/openapi-to-mcpserver/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// configTemplate is a YAML overlay merged into the generated configuration.
+// server is merged into the server configuration, and tools.requestTemplate
+// and tools.responseTemplate into the templates of every tool.
+type configTemplate struct {
+	Server map[string]interface{} `yaml:"server"`
+	Tools  struct {
+		RequestTemplate  map[string]interface{} `yaml:"requestTemplate"`
+		ResponseTemplate map[string]interface{} `yaml:"responseTemplate"`
+	} `yaml:"tools"`
+}
+
+// parseTemplate parses the template overlay of the conversion options
+func parseTemplate(content string) (*configTemplate, error) {
+	var template configTemplate
+	if err := yaml.Unmarshal([]byte(content), &template); err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return &template, nil
+}
+
+// applyTemplate deep-merges a template overlay into the configuration
+func applyTemplate(config *models.MCPConfig, template *configTemplate) error {
+	if err := mergeOverlay(&config.Server, template.Server); err != nil {
+		return fmt.Errorf("failed to apply server template: %w", err)
+	}
+	for i := range config.Tools {
+		tool := &config.Tools[i]
+		if err := mergeOverlay(&tool.RequestTemplate, template.Tools.RequestTemplate); err != nil {
+			return fmt.Errorf("failed to apply request template to tool %s: %w", tool.Name, err)
+		}
+		if err := mergeOverlay(&tool.ResponseTemplate, template.Tools.ResponseTemplate); err != nil {
+			return fmt.Errorf("failed to apply response template to tool %s: %w", tool.Name, err)
+		}
+	}
+	return nil
+}
+
+// mergeOverlay merges overlay into target through their YAML representation,
+// so that the overlay uses the same field names as the generated output.
+// target must be a pointer.
+func mergeOverlay(target interface{}, overlay map[string]interface{}) error {
+	if len(overlay) == 0 {
+		return nil
+	}
+
+	data, err := yaml.Marshal(target)
+	if err != nil {
+		return err
+	}
+	current := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &current); err != nil {
+		return err
+	}
+
+	merged, err := yaml.Marshal(deepMerge(current, overlay))
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(merged, target)
+}
+
+// deepMerge merges overlay into base. Maps are merged recursively, lists are
+// appended, and any other overlay value replaces the base value. List items
+// with a key field, such as headers, replace the base item with the same key.
+func deepMerge(base, overlay map[string]interface{}) map[string]interface{} {
+	for key, value := range overlay {
+		switch overlayValue := value.(type) {
+		case map[string]interface{}:
+			if baseValue, ok := base[key].(map[string]interface{}); ok {
+				base[key] = deepMerge(baseValue, overlayValue)
+				continue
+			}
+		case []interface{}:
+			if baseValue, ok := base[key].([]interface{}); ok {
+				base[key] = mergeLists(baseValue, overlayValue)
+				continue
+			}
+		}
+		base[key] = value
+	}
+	return base
+}
+
+// mergeLists appends the overlay items to base, replacing keyed items (maps
+// with a "key" field, compared case-insensitively) that are already present
+func mergeLists(base, overlay []interface{}) []interface{} {
+	merged := append([]interface{}(nil), base...)
+	for _, item := range overlay {
+		key, keyed := listItemKey(item)
+		replaced := false
+		if keyed {
+			for i, existing := range merged {
+				if existingKey, ok := listItemKey(existing); ok && strings.EqualFold(existingKey, key) {
+					merged[i] = item
+					replaced = true
+					break
+				}
+			}
+		}
+		if !replaced {
+			merged = append(merged, item)
+		}
+	}
+	return merged
+}
+
+// listItemKey returns the key field of a list item, if it has one
+func listItemKey(item interface{}) (string, bool) {
+	fields, ok := item.(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	key, ok := fields["key"].(string)
+	return key, ok
+}
@@ -0,0 +1,14 @@
+package mcpserver
+
+import "github.com/higress-group/openapi-to-mcpserver/internal/models"
+
+// missingArgs lists the required arguments absent from args
+func missingArgs(tool *models.Tool, args map[string]interface{}) []string {
+	var missing []string
+	for _, arg := range tool.Args {
+		if _, ok := args[arg.Name]; arg.Required && !ok {
+			missing = append(missing, arg.Name)
+		}
+	}
+	return missing
+}
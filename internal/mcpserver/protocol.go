@@ -0,0 +1,89 @@
+package mcpserver
+
+import "encoding/json"
+
+// jsonRPCVersion is the JSON-RPC version used by MCP
+const jsonRPCVersion = "2.0"
+
+// Protocol versions supported by the server, latest first
+var supportedProtocolVersions = []string{"2025-03-26", "2024-11-05"}
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+// request is a JSON-RPC request or notification. Notifications have no id.
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// isNotification reports whether the request expects no response
+func (r *request) isNotification() bool {
+	return len(r.ID) == 0
+}
+
+// response is a JSON-RPC response
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError is a JSON-RPC error object
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// initializeParams are the parameters of the initialize request
+type initializeParams struct {
+	ProtocolVersion string `json:"protocolVersion"`
+}
+
+// initializeResult is the result of the initialize request
+type initializeResult struct {
+	ProtocolVersion string                 `json:"protocolVersion"`
+	Capabilities    map[string]interface{} `json:"capabilities"`
+	ServerInfo      implementation         `json:"serverInfo"`
+}
+
+// implementation names an MCP client or server
+type implementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// callToolParams are the parameters of the tools/call request
+type callToolParams struct {
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments"`
+}
+
+// callToolResult is the result of the tools/call request
+type callToolResult struct {
+	Content []content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
+}
+
+// content is a text content block of a tool result
+type content struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// textResult creates a tool result holding a single text block
+func textResult(text string, isError bool) *callToolResult {
+	return &callToolResult{
+		Content: []content{{Type: "text", Text: text}},
+		IsError: isError,
+	}
+}
@@ -0,0 +1,253 @@
+package mcpserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/higress-group/openapi-to-mcpserver/internal/models"
+)
+
+// Argument positions of models.Arg
+const (
+	positionPath   = "path"
+	positionQuery  = "query"
+	positionHeader = "header"
+	positionCookie = "cookie"
+	positionBody   = "body"
+)
+
+// maxResponseSize limits the backend response body read into a tool result
+const maxResponseSize = 10 << 20
+
+// invoke calls the backend of a tool with the given arguments and converts
+// the response into a tool result
+func (s *Server) invoke(ctx context.Context, tool *models.Tool, args map[string]interface{}) *callToolResult {
+	req, err := s.buildRequest(ctx, tool, args)
+	if err != nil {
+		return textResult(fmt.Sprintf("failed to build request: %v", err), true)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return textResult(fmt.Sprintf("request failed: %v", err), true)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return textResult(fmt.Sprintf("failed to read response: %v", err), true)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return textResult(fmt.Sprintf("request failed with status %d: %s", resp.StatusCode, body), true)
+	}
+
+	text, err := applyResponseTemplate(&tool.ResponseTemplate, body)
+	if err != nil {
+		return textResult(fmt.Sprintf("failed to render response: %v", err), true)
+	}
+	return textResult(text, false)
+}
+
+// buildRequest renders the request template of a tool. Arguments with a
+// position are placed in the path, query, headers, cookies or body; the
+// others follow the argsTo* flags of the template and default to the body.
+// The body arguments are keyed by their name in the request.
+func (s *Server) buildRequest(ctx context.Context, tool *models.Tool, args map[string]interface{}) (*http.Request, error) {
+	rt := &tool.RequestTemplate
+	data := map[string]interface{}{
+		"config": s.config.Server.Config,
+		"args":   args,
+	}
+
+	rawURL, err := render(rt.URL, data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url template: %w", err)
+	}
+
+	headers := http.Header{}
+	for _, header := range rt.Headers {
+		value, err := render(header.Value, data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template of header %s: %w", header.Key, err)
+		}
+		headers.Add(header.Key, value)
+	}
+
+	query := url.Values{}
+	var cookies []string
+	bodyArgs := map[string]interface{}{}
+	for _, arg := range tool.Args {
+		value, ok := args[arg.Name]
+		if !ok {
+			continue
+		}
+		// Renamed arguments keep their original name in the request
+		name := arg.RequestName()
+		switch arg.Position {
+		case positionPath:
+			rawURL = strings.ReplaceAll(rawURL, "{"+name+"}", url.PathEscape(formatValue(value)))
+		case positionQuery:
+			addQueryValue(query, name, value)
+		case positionHeader:
+			headers.Set(name, formatValue(value))
+		case positionCookie:
+			cookies = append(cookies, name+"="+url.QueryEscape(formatValue(value)))
+		case positionBody:
+			bodyArgs[name] = value
+		default:
+			if rt.ArgsToUrlParam {
+				addQueryValue(query, name, value)
+			} else {
+				bodyArgs[name] = value
+			}
+		}
+	}
+	if len(cookies) > 0 {
+		if existing := headers.Get("Cookie"); existing != "" {
+			cookies = append([]string{existing}, cookies...)
+		}
+		headers.Set("Cookie", strings.Join(cookies, "; "))
+	}
+
+	requestURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url %q: %w", rawURL, err)
+	}
+	if len(query) > 0 {
+		values := requestURL.Query()
+		for key, list := range query {
+			values[key] = append(values[key], list...)
+		}
+		requestURL.RawQuery = values.Encode()
+	}
+
+	body, err := s.requestBody(ctx, tool, headers, bodyArgs, data)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, rt.Method, requestURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = headers
+	return req, nil
+}
+
+// requestBody encodes the request body: an explicit body template, a
+// multipart form or raw file content when the arguments call for it, the
+// body arguments as a form when requested by the template or content type,
+// and as JSON otherwise
+func (s *Server) requestBody(ctx context.Context, tool *models.Tool, headers http.Header, bodyArgs map[string]interface{}, data map[string]interface{}) (io.Reader, error) {
+	rt := &tool.RequestTemplate
+	if rt.Body != "" {
+		body, err := render(rt.Body, data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid body template: %w", err)
+		}
+		return strings.NewReader(body), nil
+	}
+	if len(bodyArgs) == 0 {
+		return nil, nil
+	}
+
+	contentType := headers.Get("Content-Type")
+	if strings.HasPrefix(contentType, "multipart/form-data") {
+		return s.multipartBody(ctx, tool, headers, bodyArgs)
+	}
+	if file, ok := rawFileArg(tool, bodyArgs); ok {
+		content, err := s.fileContent(ctx, bodyArgs[file.RequestName()])
+		if err != nil {
+			return nil, fmt.Errorf("invalid file argument %s: %w", file.Name, err)
+		}
+		if contentType == "" {
+			headers.Set("Content-Type", "application/octet-stream")
+		}
+		return bytes.NewReader(content), nil
+	}
+	if rt.ArgsToFormBody || strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		form := url.Values{}
+		for name, value := range bodyArgs {
+			addQueryValue(form, name, value)
+		}
+		if contentType == "" {
+			headers.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		return strings.NewReader(form.Encode()), nil
+	}
+
+	body, err := json.Marshal(bodyArgs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode body: %w", err)
+	}
+	if contentType == "" {
+		headers.Set("Content-Type", "application/json")
+	}
+	return bytes.NewReader(body), nil
+}
+
+// applyResponseTemplate turns a backend response into the tool result text.
+// A body template replaces the response, rendered with the decoded JSON
+// response as data; otherwise prependBody and appendBody surround it.
+func applyResponseTemplate(rt *models.ResponseTemplate, body []byte) (string, error) {
+	if rt.Body != "" {
+		var data interface{}
+		if err := json.Unmarshal(body, &data); err != nil {
+			data = string(body)
+		}
+		return render(rt.Body, data)
+	}
+	return rt.PrependBody + string(body) + rt.AppendBody, nil
+}
+
+// isBodyArg reports whether an argument may be sent in the request body.
+// Arguments in other positions can share its name in the request.
+func isBodyArg(arg models.Arg) bool {
+	return arg.Position == positionBody || arg.Position == ""
+}
+
+// addQueryValue adds an argument to query values, repeating the key for
+// arrays
+func addQueryValue(values url.Values, name string, value interface{}) {
+	if list, ok := value.([]interface{}); ok {
+		for _, item := range list {
+			values.Add(name, formatValue(item))
+		}
+		return
+	}
+	values.Add(name, formatValue(value))
+}
+
+// formatValue formats an argument value for the URL or headers. Objects are
+// encoded as JSON.
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool, json.Number:
+		return fmt.Sprint(v)
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = formatValue(item)
+		}
+		return strings.Join(items, ",")
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	}
+}
@@ -0,0 +1,212 @@
+// Package mcpserver serves the tools of a generated MCP configuration over
+// the MCP protocol, calling the backend API described by each tool's
+// request template.
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/higress-group/openapi-to-mcpserver/internal/models"
+)
+
+// serverVersion is reported to clients in the initialize result
+const serverVersion = "1.0.0"
+
+// defaultTimeout is the backend request timeout of the default HTTP client
+const defaultTimeout = 30 * time.Second
+
+// Server executes the tools of an MCP configuration
+type Server struct {
+	config *models.MCPConfig
+	client *http.Client
+	tools  map[string]*models.Tool
+	// order lists the served tool names in configuration order
+	order []string
+	// allowedOrigins are the browser origins accepted by the HTTP transport
+	// in addition to loopback ones
+	allowedOrigins []string
+	// fileURLHosts are the hosts file arguments may be downloaded from
+	fileURLHosts []string
+}
+
+// NewServer creates a server for config. Backend requests are sent with
+// client, or with a client using a 30 second timeout when client is nil.
+func NewServer(config *models.MCPConfig, client *http.Client) *Server {
+	if client == nil {
+		client = &http.Client{Timeout: defaultTimeout}
+	}
+
+	s := &Server{
+		config: config,
+		client: client,
+		tools:  make(map[string]*models.Tool, len(config.Tools)),
+	}
+
+	// Only the allowed tools are served when the configuration restricts them
+	allowed := make(map[string]bool, len(config.Server.AllowTools))
+	for _, name := range config.Server.AllowTools {
+		allowed[name] = true
+	}
+	for i := range config.Tools {
+		tool := &config.Tools[i]
+		if len(allowed) > 0 && !allowed[tool.Name] {
+			continue
+		}
+		if _, exists := s.tools[tool.Name]; exists {
+			continue
+		}
+		s.tools[tool.Name] = tool
+		s.order = append(s.order, tool.Name)
+	}
+	return s
+}
+
+// SetAllowedOrigins sets the browser origins, e.g. https://app.example.com,
+// accepted by the Streamable HTTP transport in addition to loopback origins
+// such as http://localhost:6274. "*" accepts every origin.
+func (s *Server) SetAllowedOrigins(origins []string) {
+	s.allowedOrigins = origins
+}
+
+// SetFileURLHosts allows file arguments to be given as http(s) URLs of the
+// given hosts, e.g. files.example.com or *.example.com, which the server
+// downloads and uploads to the backend. "*" allows every host. Downloads are
+// disabled by default, since the URLs come from the model and could point to
+// internal services.
+func (s *Server) SetFileURLHosts(hosts []string) {
+	s.fileURLHosts = hosts
+}
+
+// HandleMessage processes a single JSON-RPC message and returns the encoded
+// response, or nil for notifications
+func (s *Server) HandleMessage(ctx context.Context, message []byte) []byte {
+	var req request
+	if err := json.Unmarshal(message, &req); err != nil {
+		return encodeResponse(&response{
+			JSONRPC: jsonRPCVersion,
+			ID:      json.RawMessage("null"),
+			Error:   &rpcError{Code: codeParseError, Message: "parse error: " + err.Error()},
+		})
+	}
+	if req.JSONRPC != jsonRPCVersion || req.Method == "" {
+		if req.isNotification() {
+			return nil
+		}
+		return encodeResponse(&response{
+			JSONRPC: jsonRPCVersion,
+			ID:      req.ID,
+			Error:   &rpcError{Code: codeInvalidRequest, Message: "invalid request"},
+		})
+	}
+
+	result, rpcErr := s.dispatch(ctx, &req)
+	if req.isNotification() {
+		return nil
+	}
+	return encodeResponse(&response{
+		JSONRPC: jsonRPCVersion,
+		ID:      req.ID,
+		Result:  result,
+		Error:   rpcErr,
+	})
+}
+
+// dispatch runs the handler of a request method
+func (s *Server) dispatch(ctx context.Context, req *request) (interface{}, *rpcError) {
+	switch req.Method {
+	case "initialize":
+		return s.initialize(req.Params)
+	case "ping":
+		return struct{}{}, nil
+	case "tools/list":
+		return s.listTools(), nil
+	case "tools/call":
+		return s.callTool(ctx, req.Params)
+	default:
+		// Notifications such as notifications/initialized need no handling
+		return nil, &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("method not found: %s", req.Method)}
+	}
+}
+
+// initialize negotiates the protocol version and announces the capabilities
+func (s *Server) initialize(params json.RawMessage) (interface{}, *rpcError) {
+	var p initializeParams
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: "invalid params: " + err.Error()}
+		}
+	}
+
+	// Answer with the requested version when supported, otherwise the latest
+	version := supportedProtocolVersions[0]
+	for _, supported := range supportedProtocolVersions {
+		if p.ProtocolVersion == supported {
+			version = supported
+			break
+		}
+	}
+
+	return &initializeResult{
+		ProtocolVersion: version,
+		Capabilities: map[string]interface{}{
+			"tools": map[string]interface{}{},
+		},
+		ServerInfo: implementation{
+			Name:    s.config.Server.Name,
+			Version: serverVersion,
+		},
+	}, nil
+}
+
+// listTools describes every served tool
+func (s *Server) listTools() *models.MCPToolList {
+	result := &models.MCPToolList{Tools: make([]models.MCPTool, 0, len(s.order))}
+	for _, name := range s.order {
+		tool := s.tools[name]
+		result.Tools = append(result.Tools, models.MCPTool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: tool.InputSchema(),
+			Annotations: tool.Annotations,
+		})
+	}
+	return result
+}
+
+// callTool calls the backend of a tool. Backend failures are reported as
+// tool results with isError set, so that the model can see them.
+func (s *Server) callTool(ctx context.Context, params json.RawMessage) (interface{}, *rpcError) {
+	var p callToolParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: "invalid params: " + err.Error()}
+	}
+	tool, ok := s.tools[p.Name]
+	if !ok {
+		return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("unknown tool: %s", p.Name)}
+	}
+	if p.Arguments == nil {
+		p.Arguments = map[string]interface{}{}
+	}
+
+	if missing := missingArgs(tool, p.Arguments); len(missing) > 0 {
+		return textResult(fmt.Sprintf("missing required arguments: %v", missing), true), nil
+	}
+	return s.invoke(ctx, tool, p.Arguments), nil
+}
+
+// encodeResponse encodes a JSON-RPC response
+func encodeResponse(resp *response) []byte {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		data, _ = json.Marshal(&response{
+			JSONRPC: jsonRPCVersion,
+			ID:      resp.ID,
+			Error:   &rpcError{Code: codeInternalError, Message: "failed to encode response: " + err.Error()},
+		})
+	}
+	return data
+}
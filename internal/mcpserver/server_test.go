@@ -0,0 +1,438 @@
+package mcpserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/higress-group/openapi-to-mcpserver/internal/models"
+)
+
+// echoedRequest is what the test backend reports about a request it received
+type echoedRequest struct {
+	Method        string              `json:"method"`
+	Path          string              `json:"path"`
+	Query         map[string][]string `json:"query"`
+	Authorization string              `json:"authorization"`
+	RequestID     string              `json:"requestId"`
+	APIVersion    string              `json:"apiVersion"`
+	ContentType   string              `json:"contentType"`
+	Body          string              `json:"body"`
+}
+
+// newBackend starts a backend echoing every request as JSON, except for
+// /fail which answers with an error status, /file which serves a file and
+// /redirect which redirects to the location given in its query
+func newBackend(t *testing.T) *httptest.Server {
+	t.Helper()
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fail":
+			http.Error(w, "backend exploded", http.StatusInternalServerError)
+			return
+		case "/file":
+			w.Write([]byte("file content"))
+			return
+		case "/redirect":
+			http.Redirect(w, r, r.URL.Query().Get("to"), http.StatusFound)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(echoedRequest{
+			Method:        r.Method,
+			Path:          r.URL.Path,
+			Query:         r.URL.Query(),
+			Authorization: r.Header.Get("Authorization"),
+			RequestID:     r.Header.Get("X-Request-Id"),
+			APIVersion:    r.Header.Get("X-Api-Version"),
+			ContentType:   r.Header.Get("Content-Type"),
+			Body:          string(body),
+		})
+	}))
+	t.Cleanup(backend.Close)
+	return backend
+}
+
+// newTestServer creates a server whose tools call backend
+func newTestServer(backend *httptest.Server) *Server {
+	config := &models.MCPConfig{
+		Server: models.ServerConfig{
+			Name:   "test-server",
+			Config: map[string]interface{}{"token": "secret"},
+		},
+		Tools: []models.Tool{
+			{
+				Name:        "getPet",
+				Description: "Get a pet",
+				Args: []models.Arg{
+					{Name: "petId", Type: "integer", Required: true, Position: positionPath},
+					{Name: "fields", Type: "array", Items: map[string]interface{}{"type": "string"}, Position: positionQuery},
+					{Name: "X-Request-Id", Type: "string", Position: positionHeader},
+				},
+				RequestTemplate: models.RequestTemplate{
+					URL:     backend.URL + "/pets/{petId}",
+					Method:  http.MethodGet,
+					Headers: []models.Header{{Key: "Authorization", Value: "Bearer {{.config.token}}"}},
+				},
+				ResponseTemplate: models.ResponseTemplate{PrependBody: "# Pet\n"},
+			},
+			{
+				Name:        "createPet",
+				Description: "Create a pet",
+				Args: []models.Arg{
+					{Name: "name", Type: "string", Required: true, Position: positionBody},
+					{Name: "tag", Type: "string", Position: positionBody},
+				},
+				RequestTemplate: models.RequestTemplate{
+					URL:            backend.URL + "/pets",
+					Method:         http.MethodPost,
+					Headers:        []models.Header{{Key: "Content-Type", Value: "application/json"}},
+					ArgsToJsonBody: true,
+				},
+			},
+			{
+				// Arguments renamed by the collision handling of the converter
+				Name:        "updateItem",
+				Description: "Update an item",
+				Args: []models.Arg{
+					{Name: "id", Type: "string", Required: true, Position: positionPath},
+					{Name: "version", Type: "integer", Position: positionQuery},
+					{Name: "header_version", Type: "string", Position: positionHeader, OriginalName: "X-Api-Version"},
+					{Name: "body_id", Type: "string", Position: positionBody, OriginalName: "id"},
+					{Name: "body_version", Type: "integer", Position: positionBody, OriginalName: "version"},
+					{Name: "name", Type: "string", Required: true, Position: positionBody},
+				},
+				RequestTemplate: models.RequestTemplate{
+					URL:            backend.URL + "/items/{id}",
+					Method:         http.MethodPut,
+					Headers:        []models.Header{{Key: "Content-Type", Value: "application/json"}},
+					ArgsToJsonBody: true,
+				},
+			},
+			{
+				// The request body passed as a single nested argument
+				Name:        "replaceItem",
+				Description: "Replace an item",
+				Args: []models.Arg{
+					{Name: "id", Type: "string", Required: true, Position: positionPath},
+					{
+						Name:     "body",
+						Type:     "object",
+						Required: true,
+						Position: positionBody,
+						Properties: map[string]interface{}{
+							"id":   map[string]interface{}{"type": "string"},
+							"tags": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+						},
+						RequiredProperties: []string{"id"},
+					},
+				},
+				RequestTemplate: models.RequestTemplate{
+					URL:     backend.URL + "/items/{id}",
+					Method:  http.MethodPut,
+					Headers: []models.Header{{Key: "Content-Type", Value: "application/json"}},
+					Body:    "{{toJson .args.body}}",
+				},
+			},
+			{
+				Name:        "fail",
+				Description: "Always fails",
+				Args:        []models.Arg{},
+				RequestTemplate: models.RequestTemplate{
+					URL:    backend.URL + "/fail",
+					Method: http.MethodGet,
+				},
+			},
+		},
+	}
+	return NewServer(config, backend.Client())
+}
+
+// roundTrip sends JSON-RPC messages to a server and returns its responses
+type roundTrip func(t *testing.T, messages ...string) []response
+
+// stdioRoundTrip sends the messages over the stdio transport
+func stdioRoundTrip(server *Server) roundTrip {
+	return func(t *testing.T, messages ...string) []response {
+		t.Helper()
+		var out bytes.Buffer
+		in := strings.NewReader(strings.Join(messages, "\n") + "\n")
+		if err := server.ServeStdio(context.Background(), in, &out); err != nil {
+			t.Fatalf("ServeStdio: %v", err)
+		}
+
+		var responses []response
+		scanner := bufio.NewScanner(&out)
+		for scanner.Scan() {
+			responses = append(responses, decodeResponse(t, scanner.Bytes()))
+		}
+		return responses
+	}
+}
+
+// httpRoundTrip posts each message to the Streamable HTTP transport
+func httpRoundTrip(server *Server) roundTrip {
+	return func(t *testing.T, messages ...string) []response {
+		t.Helper()
+		endpoint := httptest.NewServer(server)
+		defer endpoint.Close()
+
+		var responses []response
+		for _, message := range messages {
+			resp, err := http.Post(endpoint.URL, "application/json", strings.NewReader(message))
+			if err != nil {
+				t.Fatalf("POST: %v", err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusAccepted {
+				continue
+			}
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("POST returned status %d: %s", resp.StatusCode, body)
+			}
+			responses = append(responses, decodeResponse(t, body))
+		}
+		return responses
+	}
+}
+
+// decodeResponse decodes a JSON-RPC response, keeping the result raw
+func decodeResponse(t *testing.T, data []byte) response {
+	t.Helper()
+	var resp struct {
+		response
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		t.Fatalf("invalid response %s: %v", data, err)
+	}
+	resp.response.Result = resp.Result
+	return resp.response
+}
+
+// toolResult decodes the tools/call result of a response
+func toolResult(t *testing.T, resp response) callToolResult {
+	t.Helper()
+	if resp.Error != nil {
+		t.Fatalf("unexpected error: %+v", resp.Error)
+	}
+	var result callToolResult
+	if err := json.Unmarshal(resp.Result.(json.RawMessage), &result); err != nil {
+		t.Fatalf("invalid tools/call result: %v", err)
+	}
+	if len(result.Content) != 1 {
+		t.Fatalf("expected one content block, got %d", len(result.Content))
+	}
+	return result
+}
+
+// echoed decodes the backend echo of a successful tool result
+func echoed(t *testing.T, result callToolResult, prefix string) echoedRequest {
+	t.Helper()
+	if result.IsError {
+		t.Fatalf("unexpected tool error: %s", result.Content[0].Text)
+	}
+	text := result.Content[0].Text
+	if !strings.HasPrefix(text, prefix) {
+		t.Fatalf("expected the result to start with %q, got %q", prefix, text)
+	}
+	var echo echoedRequest
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(text, prefix)), &echo); err != nil {
+		t.Fatalf("invalid backend echo %q: %v", text, err)
+	}
+	return echo
+}
+
+func TestTransports(t *testing.T) {
+	backend := newBackend(t)
+	transports := map[string]func(*Server) roundTrip{
+		"stdio": stdioRoundTrip,
+		"http":  httpRoundTrip,
+	}
+
+	for name, transport := range transports {
+		t.Run(name, func(t *testing.T) {
+			send := transport(newTestServer(backend))
+
+			t.Run("initialize", func(t *testing.T) {
+				responses := send(t,
+					`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}`,
+					`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+				)
+				if len(responses) != 1 {
+					t.Fatalf("expected one response, got %d", len(responses))
+				}
+				var result initializeResult
+				if err := json.Unmarshal(responses[0].Result.(json.RawMessage), &result); err != nil {
+					t.Fatalf("invalid initialize result: %v", err)
+				}
+				if result.ProtocolVersion != "2024-11-05" || result.ServerInfo.Name != "test-server" {
+					t.Errorf("unexpected initialize result: %+v", result)
+				}
+			})
+
+			t.Run("tools/list", func(t *testing.T) {
+				responses := send(t, `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)
+				var result models.MCPToolList
+				if err := json.Unmarshal(responses[0].Result.(json.RawMessage), &result); err != nil {
+					t.Fatalf("invalid tools/list result: %v", err)
+				}
+				var names []string
+				for _, tool := range result.Tools {
+					names = append(names, tool.Name)
+				}
+				if strings.Join(names, ",") != "getPet,createPet,updateItem,replaceItem,fail" {
+					t.Errorf("unexpected tools %v", names)
+				}
+				if required := result.Tools[0].InputSchema["required"]; len(required.([]interface{})) != 1 {
+					t.Errorf("expected petId to be required, got %v", required)
+				}
+			})
+
+			t.Run("path, query and header arguments", func(t *testing.T) {
+				responses := send(t, `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"getPet","arguments":{"petId":42,"fields":["name","tag"],"X-Request-Id":"abc"}}}`)
+				echo := echoed(t, toolResult(t, responses[0]), "# Pet\n")
+				if echo.Method != http.MethodGet || echo.Path != "/pets/42" {
+					t.Errorf("unexpected request line %s %s", echo.Method, echo.Path)
+				}
+				if strings.Join(echo.Query["fields"], ",") != "name,tag" {
+					t.Errorf("unexpected query %v", echo.Query)
+				}
+				if echo.RequestID != "abc" || echo.Authorization != "Bearer secret" {
+					t.Errorf("unexpected headers: request id %q, authorization %q", echo.RequestID, echo.Authorization)
+				}
+			})
+
+			t.Run("body arguments", func(t *testing.T) {
+				responses := send(t, `{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"createPet","arguments":{"name":"Rex","tag":"dog"}}}`)
+				echo := echoed(t, toolResult(t, responses[0]), "")
+				if echo.Method != http.MethodPost || echo.ContentType != "application/json" {
+					t.Errorf("unexpected request %s with content type %q", echo.Method, echo.ContentType)
+				}
+				var body map[string]interface{}
+				if err := json.Unmarshal([]byte(echo.Body), &body); err != nil || body["name"] != "Rex" || body["tag"] != "dog" {
+					t.Errorf("unexpected body %s", echo.Body)
+				}
+			})
+
+			t.Run("renamed arguments", func(t *testing.T) {
+				responses := send(t, `{"jsonrpc":"2.0","id":8,"method":"tools/call","params":{"name":"updateItem","arguments":{"id":"a1","version":3,"header_version":"2024-01","body_id":"b2","body_version":4,"name":"Box"}}}`)
+				echo := echoed(t, toolResult(t, responses[0]), "")
+				if echo.Path != "/items/a1" || strings.Join(echo.Query["version"], ",") != "3" || echo.APIVersion != "2024-01" {
+					t.Errorf("unexpected path %s, query %v and API version %q", echo.Path, echo.Query, echo.APIVersion)
+				}
+				var body map[string]interface{}
+				if err := json.Unmarshal([]byte(echo.Body), &body); err != nil {
+					t.Fatalf("invalid body %s: %v", echo.Body, err)
+				}
+				expected := map[string]interface{}{"id": "b2", "version": float64(4), "name": "Box"}
+				if len(body) != len(expected) {
+					t.Errorf("expected the body %v, got %s", expected, echo.Body)
+				}
+				for key, value := range expected {
+					if body[key] != value {
+						t.Errorf("expected body property %s to be %v, got %s", key, value, echo.Body)
+					}
+				}
+			})
+
+			t.Run("nested body argument", func(t *testing.T) {
+				responses := send(t, `{"jsonrpc":"2.0","id":9,"method":"tools/call","params":{"name":"replaceItem","arguments":{"id":"a1","body":{"id":"b2","tags":["new","sale"]}}}}`)
+				echo := echoed(t, toolResult(t, responses[0]), "")
+				if echo.Method != http.MethodPut || echo.Path != "/items/a1" || echo.ContentType != "application/json" {
+					t.Errorf("unexpected request %s %s with content type %q", echo.Method, echo.Path, echo.ContentType)
+				}
+				if echo.Body != `{"id":"b2","tags":["new","sale"]}` {
+					t.Errorf("expected the body argument to be sent as the body, got %s", echo.Body)
+				}
+			})
+
+			t.Run("missing required argument", func(t *testing.T) {
+				responses := send(t, `{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"createPet","arguments":{}}}`)
+				result := toolResult(t, responses[0])
+				if !result.IsError || !strings.Contains(result.Content[0].Text, "name") {
+					t.Errorf("expected a missing argument error, got %+v", result)
+				}
+			})
+
+			t.Run("backend error", func(t *testing.T) {
+				responses := send(t, `{"jsonrpc":"2.0","id":6,"method":"tools/call","params":{"name":"fail"}}`)
+				result := toolResult(t, responses[0])
+				if !result.IsError || !strings.Contains(result.Content[0].Text, "status 500") || !strings.Contains(result.Content[0].Text, "backend exploded") {
+					t.Errorf("expected the backend error to be reported, got %+v", result)
+				}
+			})
+
+			t.Run("unknown tool", func(t *testing.T) {
+				responses := send(t, `{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"nope"}}`)
+				if responses[0].Error == nil || responses[0].Error.Code != codeInvalidParams {
+					t.Errorf("expected an invalid params error, got %+v", responses[0])
+				}
+			})
+		})
+	}
+}
+
+func TestHTTPEventStream(t *testing.T) {
+	endpoint := httptest.NewServer(newTestServer(newBackend(t)))
+	defer endpoint.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, endpoint.URL, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Errorf("expected an event stream, got %q", resp.Header.Get("Content-Type"))
+	}
+	if !strings.HasPrefix(string(body), "event: message\ndata: {") {
+		t.Errorf("unexpected event stream %q", body)
+	}
+}
+
+func TestHTTPOrigin(t *testing.T) {
+	server := newTestServer(newBackend(t))
+	server.SetAllowedOrigins([]string{"https://app.example.com"})
+	endpoint := httptest.NewServer(server)
+	defer endpoint.Close()
+
+	tests := []struct {
+		origin string
+		status int
+	}{
+		{"", http.StatusOK},
+		{"http://localhost:6274", http.StatusOK},
+		{"http://127.0.0.1:3000", http.StatusOK},
+		{"http://[::1]:3000", http.StatusOK},
+		{"https://app.example.com", http.StatusOK},
+		{"https://evil.example.com", http.StatusForbidden},
+		{"http://localhost.evil.example.com", http.StatusForbidden},
+		{"null", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodPost, endpoint.URL, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("origin %q: expected status %d, got %d", tt.origin, tt.status, resp.StatusCode)
+		}
+	}
+}
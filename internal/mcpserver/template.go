@@ -0,0 +1,59 @@
+package mcpserver
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// templateFuncs are the helper functions available in request and response
+// templates, named after their sprig counterparts used by Higress
+var templateFuncs = template.FuncMap{
+	"b64enc": func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	},
+	"b64dec": func(s string) (string, error) {
+		data, err := base64.StdEncoding.DecodeString(s)
+		return string(data), err
+	},
+	"uuidv4": uuidv4,
+	"toJson": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"default": func(fallback, value interface{}) interface{} {
+		if value == nil || value == "" {
+			return fallback
+		}
+		return value
+	},
+}
+
+// render executes text as a Go template with data. Missing values render as
+// empty strings.
+func render(text string, data interface{}) (string, error) {
+	tmpl, err := template.New("").Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return strings.ReplaceAll(b.String(), "<no value>", ""), nil
+}
+
+// uuidv4 returns a random version 4 UUID
+func uuidv4() (string, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		return "", err
+	}
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16]), nil
+}
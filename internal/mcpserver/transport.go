@@ -0,0 +1,147 @@
+package mcpserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// maxMessageSize limits the size of a single incoming JSON-RPC message
+const maxMessageSize = 10 << 20
+
+// ServeStdio serves newline-delimited JSON-RPC messages read from in,
+// writing the responses to out, until in is closed or ctx is cancelled
+func (s *Server) ServeStdio(ctx context.Context, in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxMessageSize)
+	writer := bufio.NewWriter(out)
+
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		message := bytes.TrimSpace(scanner.Bytes())
+		if len(message) == 0 {
+			continue
+		}
+		if resp := s.handlePayload(ctx, message); resp != nil {
+			if _, err := writer.Write(append(resp, '\n')); err != nil {
+				return err
+			}
+		}
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// ServeHTTP implements the Streamable HTTP transport. Clients POST JSON-RPC
+// messages and receive the responses as JSON, or as a server-sent event
+// stream when they only accept text/event-stream. Server initiated streams
+// (GET) are not offered. Requests from browser pages of other origins are
+// rejected, so that a DNS rebinding site cannot call the tools with the
+// configured credentials.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.allowedOrigin(r.Header.Get("Origin")) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	message, err := io.ReadAll(io.LimitReader(r.Body, maxMessageSize))
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	resp := s.handlePayload(r.Context(), message)
+	if resp == nil {
+		// Notifications and responses are only acknowledged
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	accept := r.Header.Get("Accept")
+	if strings.Contains(accept, "text/event-stream") && !strings.Contains(accept, "application/json") {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "event: message\ndata: %s\n\n", resp)
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
+
+// allowedOrigin reports whether a request with the given Origin header may
+// be served. Requests without one do not come from a browser page.
+func (s *Server) allowedOrigin(origin string) bool {
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range s.allowedOrigins {
+		if allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+
+	originURL, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	switch originURL.Hostname() {
+	case "localhost", "127.0.0.1", "::1":
+		return true
+	}
+	return false
+}
+
+// handlePayload handles a single message or a batch of messages and returns
+// the encoded response, a JSON array for batches, or nil when there is
+// nothing to answer
+func (s *Server) handlePayload(ctx context.Context, payload []byte) []byte {
+	payload = bytes.TrimSpace(payload)
+	if !isBatch(payload) {
+		return s.HandleMessage(ctx, payload)
+	}
+
+	var messages []json.RawMessage
+	if err := json.Unmarshal(payload, &messages); err != nil {
+		return encodeResponse(&response{
+			JSONRPC: jsonRPCVersion,
+			ID:      json.RawMessage("null"),
+			Error:   &rpcError{Code: codeParseError, Message: "parse error: " + err.Error()},
+		})
+	}
+	var responses [][]byte
+	for _, message := range messages {
+		if resp := s.HandleMessage(ctx, message); resp != nil {
+			responses = append(responses, resp)
+		}
+	}
+	if len(responses) == 0 {
+		return nil
+	}
+	return append(append([]byte{'['}, bytes.Join(responses, []byte{','})...), ']')
+}
+
+// isBatch reports whether a trimmed payload is a JSON-RPC batch
+func isBatch(payload []byte) bool {
+	return len(payload) > 0 && payload[0] == '['
+}
@@ -0,0 +1,227 @@
+package mcpserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+
+	"github.com/higress-group/openapi-to-mcpserver/internal/models"
+)
+
+// maxFileSize limits the size of a file downloaded for a file argument
+const maxFileSize = 50 << 20
+
+// rawFileArg returns the file argument of a tool whose body is raw file
+// content: a single body argument with the file encoding
+func rawFileArg(tool *models.Tool, bodyArgs map[string]interface{}) (models.Arg, bool) {
+	if len(bodyArgs) != 1 {
+		return models.Arg{}, false
+	}
+	for _, arg := range tool.Args {
+		if _, ok := bodyArgs[arg.RequestName()]; ok && isBodyArg(arg) && arg.Encoding == models.FileEncoding {
+			return arg, true
+		}
+	}
+	return models.Arg{}, false
+}
+
+// multipartBody encodes the body arguments as multipart/form-data. File
+// arguments become file parts, arguments with a content type are encoded
+// accordingly and the others become plain form fields.
+func (s *Server) multipartBody(ctx context.Context, tool *models.Tool, headers http.Header, bodyArgs map[string]interface{}) (io.Reader, error) {
+	var b bytes.Buffer
+	writer := multipart.NewWriter(&b)
+
+	for _, arg := range tool.Args {
+		name := arg.RequestName()
+		value, ok := bodyArgs[name]
+		if !ok || !isBodyArg(arg) {
+			continue
+		}
+
+		if arg.Encoding == models.FileEncoding {
+			// An array of files is sent as one part per file
+			files, isList := value.([]interface{})
+			if !isList {
+				files = []interface{}{value}
+			}
+			for _, file := range files {
+				content, err := s.fileContent(ctx, file)
+				if err != nil {
+					return nil, fmt.Errorf("invalid file argument %s: %w", arg.Name, err)
+				}
+				if err := writePart(writer, name, name, partContentType(arg, "application/octet-stream"), content); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		if arg.ContentType != "" {
+			content := []byte(formatValue(value))
+			if strings.Contains(arg.ContentType, "json") {
+				encoded, err := json.Marshal(value)
+				if err != nil {
+					return nil, fmt.Errorf("failed to encode argument %s: %w", arg.Name, err)
+				}
+				content = encoded
+			}
+			if err := writePart(writer, name, "", arg.ContentType, content); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// Arrays of plain values repeat the field
+		values, isList := value.([]interface{})
+		if !isList {
+			values = []interface{}{value}
+		}
+		for _, item := range values {
+			if err := writer.WriteField(name, formatValue(item)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	headers.Set("Content-Type", writer.FormDataContentType())
+	return &b, nil
+}
+
+// writePart writes a multipart part, as a file when filename is set
+func writePart(writer *multipart.Writer, name, filename, contentType string, content []byte) error {
+	disposition := fmt.Sprintf(`form-data; name="%s"`, escapeQuotes(name))
+	if filename != "" {
+		disposition += fmt.Sprintf(`; filename="%s"`, escapeQuotes(filename))
+	}
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", disposition)
+	header.Set("Content-Type", contentType)
+
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	_, err = part.Write(content)
+	return err
+}
+
+// partContentType returns the first content type declared for a part, or
+// fallback. The encoding object may list several, separated by commas.
+func partContentType(arg models.Arg, fallback string) string {
+	contentType := strings.TrimSpace(strings.Split(arg.ContentType, ",")[0])
+	if contentType == "" || strings.Contains(contentType, "*") {
+		return fallback
+	}
+	return contentType
+}
+
+// fileContent returns the content of a file argument: downloaded when the
+// value is an http(s) URL of an allowed host, base64 decoded otherwise
+func (s *Server) fileContent(ctx context.Context, value interface{}) ([]byte, error) {
+	text, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected a base64 string or a URL")
+	}
+
+	if strings.HasPrefix(text, "http://") || strings.HasPrefix(text, "https://") {
+		return s.downloadFile(ctx, text)
+	}
+
+	// Accept data URLs as well as plain base64
+	if strings.HasPrefix(text, "data:") {
+		if comma := strings.Index(text, ","); comma >= 0 {
+			text = text[comma+1:]
+		}
+	}
+	content, err := base64.StdEncoding.DecodeString(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 content: %w", err)
+	}
+	return content, nil
+}
+
+// downloadFile downloads the content of a file argument given as a URL. The
+// value comes from the model, so only the hosts allowed by SetFileURLHosts
+// may be contacted, also when following redirects, and the content is
+// limited to maxFileSize.
+func (s *Server) downloadFile(ctx context.Context, rawURL string) ([]byte, error) {
+	fileURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file URL: %w", err)
+	}
+	if !s.allowedFileURL(fileURL) {
+		return nil, fmt.Errorf("downloading files from %s is not allowed, pass the base64 encoded content instead", fileURL.Host)
+	}
+
+	client := *s.client
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return fmt.Errorf("stopped after 10 redirects")
+		}
+		if !s.allowedFileURL(req.URL) {
+			return fmt.Errorf("redirect to %s is not allowed", req.URL.Host)
+		}
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("failed to download %s: status %d", rawURL, resp.StatusCode)
+	}
+	if resp.ContentLength > maxFileSize {
+		return nil, fmt.Errorf("file %s exceeds %d bytes", rawURL, maxFileSize)
+	}
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", rawURL, err)
+	}
+	if len(content) > maxFileSize {
+		return nil, fmt.Errorf("file %s exceeds %d bytes", rawURL, maxFileSize)
+	}
+	return content, nil
+}
+
+// allowedFileURL reports whether file arguments may be downloaded from a
+// URL. Hosts are matched exactly or, for patterns like *.example.com, by
+// subdomain; "*" allows every host.
+func (s *Server) allowedFileURL(fileURL *url.URL) bool {
+	if fileURL.Scheme != "http" && fileURL.Scheme != "https" {
+		return false
+	}
+	host := strings.ToLower(fileURL.Hostname())
+	for _, allowed := range s.fileURLHosts {
+		allowed = strings.ToLower(allowed)
+		switch {
+		case allowed == "*", allowed == host:
+			return true
+		case strings.HasPrefix(allowed, "*.") && strings.HasSuffix(host, allowed[1:]):
+			return true
+		}
+	}
+	return false
+}
+
+// escapeQuotes escapes a multipart header parameter value
+func escapeQuotes(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
@@ -0,0 +1,121 @@
+package mcpserver
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/higress-group/openapi-to-mcpserver/internal/models"
+)
+
+// uploadTool sends a single file argument as the raw request body
+func uploadTool(backendURL string) *models.Tool {
+	return &models.Tool{
+		Name: "uploadFile",
+		Args: []models.Arg{
+			{Name: "file", Type: "string", Required: true, Position: positionBody, Encoding: models.FileEncoding},
+		},
+		RequestTemplate: models.RequestTemplate{
+			URL:     backendURL + "/upload",
+			Method:  http.MethodPost,
+			Headers: []models.Header{{Key: "Content-Type", Value: "application/octet-stream"}},
+		},
+	}
+}
+
+func TestFileArguments(t *testing.T) {
+	backend := newBackend(t)
+	backendURL, _ := url.Parse(backend.URL)
+	// The same backend under another host name
+	otherHostURL := "http://localhost:" + backendURL.Port()
+
+	tests := []struct {
+		name    string
+		hosts   []string
+		value   string
+		content string
+		err     string
+	}{
+		{
+			name:    "base64 content",
+			value:   base64.StdEncoding.EncodeToString([]byte("inline content")),
+			content: "inline content",
+		},
+		{
+			name:    "data URL",
+			value:   "data:text/plain;base64," + base64.StdEncoding.EncodeToString([]byte("inline content")),
+			content: "inline content",
+		},
+		{
+			name:  "URL downloads are disabled by default",
+			value: backend.URL + "/file",
+			err:   "is not allowed",
+		},
+		{
+			name:    "URL of an allowed host",
+			hosts:   []string{backendURL.Hostname()},
+			value:   backend.URL + "/file",
+			content: "file content",
+		},
+		{
+			name:    "URL with every host allowed",
+			hosts:   []string{"*"},
+			value:   otherHostURL + "/file",
+			content: "file content",
+		},
+		{
+			name:  "URL of another host",
+			hosts: []string{"files.example.com", "*.example.com"},
+			value: backend.URL + "/file",
+			err:   "is not allowed",
+		},
+		{
+			name:  "redirect to another host",
+			hosts: []string{backendURL.Hostname()},
+			value: backend.URL + "/redirect?to=" + url.QueryEscape(otherHostURL+"/file"),
+			err:   "redirect to localhost",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(backend)
+			server.SetFileURLHosts(tt.hosts)
+
+			result := server.invoke(context.Background(), uploadTool(backend.URL), map[string]interface{}{"file": tt.value})
+			if tt.err != "" {
+				if !result.IsError || !strings.Contains(result.Content[0].Text, tt.err) {
+					t.Fatalf("expected an error containing %q, got %+v", tt.err, result)
+				}
+				return
+			}
+			echo := echoed(t, *result, "")
+			if echo.Body != tt.content {
+				t.Errorf("expected the body %q, got %q", tt.content, echo.Body)
+			}
+		})
+	}
+}
+
+func TestAllowedFileURL(t *testing.T) {
+	server := &Server{fileURLHosts: []string{"files.example.com", "*.cdn.example.com"}}
+	tests := map[string]bool{
+		"https://files.example.com/a.png":        true,
+		"http://FILES.example.com/a.png":         true,
+		"https://img.cdn.example.com/a.png":      true,
+		"https://cdn.example.com/a.png":          false,
+		"https://evilcdn.example.com/a.png":      false,
+		"https://files.example.com.evil.io/a":    false,
+		"http://169.254.169.254/latest/metadata": false,
+		"ftp://files.example.com/a.png":          false,
+	}
+	for rawURL, want := range tests {
+		fileURL, _ := url.Parse(rawURL)
+		if got := server.allowedFileURL(fileURL); got != want {
+			t.Errorf("allowedFileURL(%s) = %v, want %v", rawURL, got, want)
+		}
+	}
+}
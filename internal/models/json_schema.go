@@ -0,0 +1,182 @@
+package models
+
+// InputSchema returns the JSON Schema of the tool's arguments
+func (t *Tool) InputSchema() map[string]interface{} {
+	properties := make(map[string]interface{}, len(t.Args))
+	required := []string{}
+	for _, arg := range t.Args {
+		properties[arg.Name] = arg.JSONSchema()
+		if arg.Required {
+			required = append(required, arg.Name)
+		}
+	}
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// JSONSchema returns the JSON Schema of the argument. OpenAPI 3.0 keywords
+// are translated: nullable becomes a "null" type, exclusive bound flags
+// become bounds and example becomes examples.
+func (a Arg) JSONSchema() map[string]interface{} {
+	schema := map[string]interface{}{}
+	if a.Type != "" {
+		schema["type"] = a.Type
+	}
+	if a.Nullable {
+		schema["nullable"] = true
+	}
+	if a.Description != "" {
+		schema["description"] = a.Description
+	}
+	if a.Default != nil {
+		schema["default"] = a.Default
+	}
+	if a.Example != nil {
+		schema["example"] = a.Example
+	}
+	if len(a.Enum) > 0 {
+		schema["enum"] = a.Enum
+	}
+	if a.Format != "" {
+		schema["format"] = a.Format
+	}
+	if a.Minimum != nil {
+		schema["minimum"] = *a.Minimum
+	}
+	if a.Maximum != nil {
+		schema["maximum"] = *a.Maximum
+	}
+	if a.ExclusiveMinimum {
+		schema["exclusiveMinimum"] = true
+	}
+	if a.ExclusiveMaximum {
+		schema["exclusiveMaximum"] = true
+	}
+	if a.MultipleOf != nil {
+		schema["multipleOf"] = *a.MultipleOf
+	}
+	if a.MinLength > 0 {
+		schema["minLength"] = a.MinLength
+	}
+	if a.MaxLength != nil {
+		schema["maxLength"] = *a.MaxLength
+	}
+	if a.Pattern != "" {
+		schema["pattern"] = a.Pattern
+	}
+	if a.MinItems > 0 {
+		schema["minItems"] = a.MinItems
+	}
+	if a.MaxItems != nil {
+		schema["maxItems"] = *a.MaxItems
+	}
+	if a.UniqueItems {
+		schema["uniqueItems"] = true
+	}
+	if a.WriteOnly {
+		schema["writeOnly"] = true
+	}
+	if a.Items != nil {
+		schema["items"] = a.Items
+	}
+	if a.Properties != nil {
+		schema["properties"] = a.Properties
+	}
+	if len(a.RequiredProperties) > 0 {
+		schema["required"] = a.RequiredProperties
+	}
+	if a.AdditionalProperties != nil {
+		schema["additionalProperties"] = a.AdditionalProperties
+	}
+	if len(a.OneOf) > 0 {
+		schema["oneOf"] = a.OneOf
+	}
+	if len(a.AnyOf) > 0 {
+		schema["anyOf"] = a.AnyOf
+	}
+	if a.Discriminator != nil {
+		schema["discriminator"] = a.Discriminator
+	}
+	return ToJSONSchema(schema)
+}
+
+// ToJSONSchema translates a schema map using OpenAPI 3.0 keywords, such as
+// the property maps of args, into JSON Schema, recursing into properties,
+// items, additionalProperties and alternatives. The input is not modified.
+func ToJSONSchema(schema map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(schema))
+	for key, value := range schema {
+		switch key {
+		case "nullable", "exclusiveMinimum", "exclusiveMaximum", "example":
+			// Translated below
+		case "properties":
+			if properties, ok := value.(map[string]interface{}); ok {
+				converted := make(map[string]interface{}, len(properties))
+				for name, property := range properties {
+					converted[name] = toJSONSchemaValue(property)
+				}
+				result[key] = converted
+				continue
+			}
+			result[key] = value
+		case "items", "additionalProperties":
+			result[key] = toJSONSchemaValue(value)
+		case "oneOf", "anyOf":
+			result[key] = toJSONSchemaList(value)
+		default:
+			result[key] = value
+		}
+	}
+
+	if nullable, _ := schema["nullable"].(bool); nullable {
+		if typ, ok := schema["type"].(string); ok && typ != "" {
+			result["type"] = []interface{}{typ, "null"}
+		}
+	}
+	for flag, bound := range map[string]string{"exclusiveMinimum": "minimum", "exclusiveMaximum": "maximum"} {
+		if exclusive, _ := schema[flag].(bool); exclusive {
+			if value, ok := schema[bound]; ok {
+				result[flag] = value
+				delete(result, bound)
+			}
+		}
+	}
+	if example, ok := schema["example"]; ok {
+		result["examples"] = []interface{}{example}
+	}
+	return result
+}
+
+// toJSONSchemaValue translates a nested schema value when it is a schema map
+func toJSONSchemaValue(value interface{}) interface{} {
+	if schema, ok := value.(map[string]interface{}); ok {
+		return ToJSONSchema(schema)
+	}
+	return value
+}
+
+// toJSONSchemaList translates a list of alternative schemas
+func toJSONSchemaList(value interface{}) interface{} {
+	switch alternatives := value.(type) {
+	case []map[string]interface{}:
+		converted := make([]interface{}, len(alternatives))
+		for i, alternative := range alternatives {
+			converted[i] = ToJSONSchema(alternative)
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, len(alternatives))
+		for i, alternative := range alternatives {
+			converted[i] = toJSONSchemaValue(alternative)
+		}
+		return converted
+	}
+	return value
+}
@@ -20,19 +20,68 @@ type Tool struct {
 	Args             []Arg            `yaml:"args"`
 	RequestTemplate  RequestTemplate  `yaml:"requestTemplate"`
 	ResponseTemplate ResponseTemplate `yaml:"responseTemplate"`
+	Annotations      *ToolAnnotations `yaml:"annotations,omitempty"`
 }
 
 // Arg represents an MCP tool argument
 type Arg struct {
-	Name        string                 `yaml:"name"`
-	Description string                 `yaml:"description"`
-	Type        string                 `yaml:"type,omitempty"`
-	Required    bool                   `yaml:"required,omitempty"`
-	Default     interface{}            `yaml:"default,omitempty"`
-	Enum        []interface{}          `yaml:"enum,omitempty"`
-	Items       map[string]interface{} `yaml:"items,omitempty"`
-	Properties  map[string]interface{} `yaml:"properties,omitempty"`
-	Position    string                 `yaml:"position,omitempty"`
+	Name        string        `yaml:"name"`
+	Description string        `yaml:"description"`
+	Type        string        `yaml:"type,omitempty"`
+	Required    bool          `yaml:"required,omitempty"`
+	Nullable    bool          `yaml:"nullable,omitempty"`
+	Default     interface{}   `yaml:"default,omitempty"`
+	Example     interface{}   `yaml:"example,omitempty"`
+	Enum        []interface{} `yaml:"enum,omitempty"`
+	// Format and the constraints below are copied from the JSON Schema of
+	// the parameter or property
+	Format           string                 `yaml:"format,omitempty"`
+	Minimum          *float64               `yaml:"minimum,omitempty"`
+	Maximum          *float64               `yaml:"maximum,omitempty"`
+	ExclusiveMinimum bool                   `yaml:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum bool                   `yaml:"exclusiveMaximum,omitempty"`
+	MultipleOf       *float64               `yaml:"multipleOf,omitempty"`
+	MinLength        uint64                 `yaml:"minLength,omitempty"`
+	MaxLength        *uint64                `yaml:"maxLength,omitempty"`
+	Pattern          string                 `yaml:"pattern,omitempty"`
+	MinItems         uint64                 `yaml:"minItems,omitempty"`
+	MaxItems         *uint64                `yaml:"maxItems,omitempty"`
+	UniqueItems      bool                   `yaml:"uniqueItems,omitempty"`
+	WriteOnly        bool                   `yaml:"writeOnly,omitempty"`
+	Items            map[string]interface{} `yaml:"items,omitempty"`
+	Properties       map[string]interface{} `yaml:"properties,omitempty"`
+	// RequiredProperties and AdditionalProperties describe an object
+	// argument: the properties it must contain, and whether (false) or in
+	// which shape (a schema map) other properties are accepted
+	RequiredProperties   []string    `yaml:"requiredProperties,omitempty"`
+	AdditionalProperties interface{} `yaml:"additionalProperties,omitempty"`
+	// OneOf/AnyOf list the alternatives of a polymorphic argument, each in
+	// the same shape as a Properties entry
+	OneOf         []map[string]interface{} `yaml:"oneOf,omitempty"`
+	AnyOf         []map[string]interface{} `yaml:"anyOf,omitempty"`
+	Discriminator map[string]interface{}   `yaml:"discriminator,omitempty"`
+	Position      string                   `yaml:"position,omitempty"`
+	// Encoding tells the runtime how the value is given. FileEncoding marks
+	// a file: base64 encoded content, or an http(s) URL to download it from
+	// when the runtime allows downloads from its host.
+	Encoding string `yaml:"encoding,omitempty"`
+	// ContentType is the content type of a multipart part, from the
+	// encoding object of the spec
+	ContentType string `yaml:"contentType,omitempty"`
+	// OriginalName is the name of the parameter or body property in the
+	// request when the argument was renamed to avoid a name collision
+	OriginalName string `yaml:"originalName,omitempty"`
+}
+
+// FileEncoding is the Arg encoding of file inputs
+const FileEncoding = "base64"
+
+// RequestName returns the name of the argument in the request
+func (a Arg) RequestName() string {
+	if a.OriginalName != "" {
+		return a.OriginalName
+	}
+	return a.Name
 }
 
 // RequestTemplate represents the MCP request template
@@ -57,6 +106,10 @@ type ResponseTemplate struct {
 	Body        string `yaml:"body,omitempty"`
 	PrependBody string `yaml:"prependBody,omitempty"`
 	AppendBody  string `yaml:"appendBody,omitempty"`
+	// StatusCode and ContentType record the response described by the
+	// template, e.g. 200 and application/json
+	StatusCode  string `yaml:"statusCode,omitempty"`
+	ContentType string `yaml:"contentType,omitempty"`
 }
 
 // ConvertOptions represents options for the conversion process
@@ -65,4 +118,87 @@ type ConvertOptions struct {
 	ServerConfig     map[string]interface{}
 	ToolNamePrefix   string
 	ResponseTemplate string // Markdown格式的响应描述模板（仅影响API响应的描述部分）
+	// DescribeErrorResponses 为 true 时在响应描述中追加 Error Responses 一节，说明每个非 2xx 响应的描述和字段
+	DescribeErrorResponses bool
+	// ResponseTextTemplate 是 Go text/template 格式的响应模板，转换时为每个工具渲染，输出作为 prependBody，
+	// 其中定义的 appendBody 模板（{{define "appendBody"}}...{{end}}）的输出作为 appendBody。
+	// 设置后替代 ResponseTemplate 和默认的响应描述
+	ResponseTextTemplate string
+
+	// ToolNameCasing 是由请求方法和路径生成工具名时使用的命名风格（snake 或 camel，默认 snake）
+	ToolNameCasing string
+	// ToolNameMaxLength 是工具名（含前缀）的最大长度，超出部分截断并追加哈希（默认 64）
+	ToolNameMaxLength int
+
+	// ServerVariables 覆盖服务器 URL 中 {variable} 变量的默认值
+	ServerVariables map[string]string
+	// ServerIndex 是在多个服务器中选用的服务器下标（默认 0）
+	ServerIndex int
+	// ServerDescription 按描述在每个操作生效的服务器列表（操作级、路径级或文档级）中选择服务器，优先于 ServerIndex
+	ServerDescription string
+	// BaseURL 用于解析相对路径的服务器 URL
+	BaseURL string
+
+	// ContentTypePreference 是请求体存在多个媒体类型时的选择顺序，支持 application/*+json 形式的通配符
+	ContentTypePreference []string
+
+	// BodyArgName 是承载整个请求体的参数名，用于数组、基本类型、文本和二进制请求体（默认 body）
+	BodyArgName string
+
+	// OutputMode 选择转换结果的形式：higress（默认，Higress MCP 服务器配置）或 mcp（MCP 标准的工具定义）
+	OutputMode string
+
+	// Template 是 YAML 格式的模板，深度合并到 server 以及每个工具的 requestTemplate/responseTemplate 中
+	Template string
+
+	// Filter 选择转换为工具的操作，为空时转换所有操作
+	Filter OperationFilter
+
+	// ArgNameCollision 选择不同位置的参数同名时的处理方式：prefix（默认）为后出现的参数名加上位置前缀，如 body_id；
+	// nest 将 JSON 请求体的参数放入一个对象参数（名称为 BodyArgName）中
+	ArgNameCollision string
+	// ArgNamePrefixes 覆盖各位置（query、header、cookie、body）的参数名前缀，默认为位置名加下划线，如 {"body": "payload_"}
+	ArgNamePrefixes map[string]string
+
+	// BodyMode 选择 JSON 对象请求体转换为参数的方式：flatten（默认）每个顶层属性一个参数；
+	// nested 整个请求体作为一个携带完整 schema 的对象参数（名称为 BodyArgName）；auto 在请求体超过下面的阈值时使用 nested
+	BodyMode string
+	// BodyMaxDepth 是 auto 模式下仍展开请求体的最大对象嵌套层数（默认 2，顶层属性为第 1 层）
+	BodyMaxDepth int
+	// BodyMaxProperties 是 auto 模式下仍展开请求体的最大属性总数，包括嵌套属性（默认 20）
+	BodyMaxProperties int
+}
+
+// 请求体的转换方式
+const (
+	BodyModeFlatten = "flatten"
+	BodyModeNested  = "nested"
+	BodyModeAuto    = "auto"
+)
+
+// 参数同名的处理方式
+const (
+	ArgNameCollisionPrefix = "prefix"
+	ArgNameCollisionNest   = "nest"
+)
+
+// OperationFilter 按标签、路径、请求方法、operationId 和废弃状态选择操作。
+// 操作需满足所有非空的 Include 条件，且不满足任何 Exclude 条件
+type OperationFilter struct {
+	// IncludeTags/ExcludeTags 匹配操作的任一标签
+	IncludeTags []string
+	ExcludeTags []string
+	// IncludePaths/ExcludePaths 是路径的 glob 模式，* 匹配一个路径段内的任意字符，** 匹配任意多个路径段，如 /pets/** 或 /users/*/orders
+	IncludePaths []string
+	ExcludePaths []string
+	// IncludeMethods/ExcludeMethods 是请求方法，不区分大小写
+	IncludeMethods []string
+	ExcludeMethods []string
+	// IncludeOperationID/ExcludeOperationID 是匹配 operationId 的正则表达式，未提供 operationId 的操作使用生成的工具名匹配
+	IncludeOperationID string
+	ExcludeOperationID string
+	// ExcludeDeprecated 排除标记为 deprecated 的操作
+	ExcludeDeprecated bool
+	// AllowTools 为 true 时仍转换所有操作，并将匹配的工具名写入 server.allowTools，而不是丢弃不匹配的操作
+	AllowTools bool
 }
@@ -0,0 +1,32 @@
+package models
+
+// Output modes of the converter
+const (
+	// OutputModeHigress produces the Higress MCP server configuration
+	OutputModeHigress = "higress"
+	// OutputModeMCP produces MCP tool definitions as listed by tools/list
+	OutputModeMCP = "mcp"
+)
+
+// MCPToolList holds MCP tool definitions in the shape of a tools/list result
+type MCPToolList struct {
+	Tools []MCPTool `json:"tools"`
+}
+
+// MCPTool is a tool definition as specified by the Model Context Protocol
+type MCPTool struct {
+	Name         string                 `json:"name"`
+	Description  string                 `json:"description,omitempty"`
+	InputSchema  map[string]interface{} `json:"inputSchema"`
+	OutputSchema map[string]interface{} `json:"outputSchema,omitempty"`
+	Annotations  *ToolAnnotations       `json:"annotations,omitempty"`
+}
+
+// ToolAnnotations are the MCP hints describing the behaviour of a tool
+type ToolAnnotations struct {
+	Title           string `json:"title,omitempty" yaml:"title,omitempty"`
+	ReadOnlyHint    *bool  `json:"readOnlyHint,omitempty" yaml:"readOnlyHint,omitempty"`
+	DestructiveHint *bool  `json:"destructiveHint,omitempty" yaml:"destructiveHint,omitempty"`
+	IdempotentHint  *bool  `json:"idempotentHint,omitempty" yaml:"idempotentHint,omitempty"`
+	OpenWorldHint   *bool  `json:"openWorldHint,omitempty" yaml:"openWorldHint,omitempty"`
+}
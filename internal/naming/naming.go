@@ -0,0 +1,198 @@
+package naming
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Casing selects how generated tool names are written
+type Casing string
+
+const (
+	// CasingSnake writes generated names as get_pets_by_pet_id
+	CasingSnake Casing = "snake"
+	// CasingCamel writes generated names as getPetsByPetId
+	CasingCamel Casing = "camel"
+)
+
+// DefaultMaxLength is the maximum tool name length accepted by MCP clients
+const DefaultMaxLength = 64
+
+// hashLength is the number of hex characters appended to shortened names
+const hashLength = 8
+
+// invalidChars matches the characters not allowed in MCP tool names
+var invalidChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// Strategy turns operations into valid MCP tool names
+type Strategy struct {
+	// Casing is applied to names generated from the method and path;
+	// explicit operationIds keep their own casing
+	Casing Casing
+	// MaxLength bounds the final name, including the prefix. Longer names
+	// are shortened and suffixed with a hash of the full name.
+	MaxLength int
+	// Prefix is prepended to every name
+	Prefix string
+}
+
+// NewStrategy creates a naming strategy, filling in defaults for empty values
+func NewStrategy(casing Casing, maxLength int, prefix string) (Strategy, error) {
+	switch casing {
+	case "":
+		casing = CasingSnake
+	case CasingSnake, CasingCamel:
+	default:
+		return Strategy{}, fmt.Errorf("unsupported tool name casing %q, expected %q or %q", casing, CasingSnake, CasingCamel)
+	}
+	if maxLength <= 0 {
+		maxLength = DefaultMaxLength
+	}
+	if maxLength <= hashLength+1 {
+		return Strategy{}, fmt.Errorf("tool name max length must be greater than %d", hashLength+1)
+	}
+	return Strategy{Casing: casing, MaxLength: maxLength, Prefix: prefix}, nil
+}
+
+// ToolName returns the tool name of an operation: the sanitized operationId
+// when present, otherwise a name generated from the method and path
+func (s Strategy) ToolName(operationID, method, path string) string {
+	name := Sanitize(operationID)
+	if name == "" {
+		name = Generate(method, path, s.Casing)
+	}
+	return s.shorten(Sanitize(s.Prefix + name))
+}
+
+// shorten truncates names longer than MaxLength, keeping them unique by
+// appending a hash of the full name
+func (s Strategy) shorten(name string) string {
+	if s.MaxLength <= 0 || len(name) <= s.MaxLength {
+		return name
+	}
+	sum := sha1.Sum([]byte(name))
+	return name[:s.MaxLength-hashLength-1] + "_" + hex.EncodeToString(sum[:])[:hashLength]
+}
+
+// Sanitize replaces characters not allowed in tool names with underscores
+func Sanitize(name string) string {
+	name = invalidChars.ReplaceAllString(name, "_")
+	for strings.Contains(name, "__") {
+		name = strings.ReplaceAll(name, "__", "_")
+	}
+	return strings.Trim(name, "_")
+}
+
+// Generate builds a name from an HTTP method and path, e.g. GET
+// /pets/{petId}/toys becomes get_pets_by_pet_id_toys in snake case
+func Generate(method, path string, casing Casing) string {
+	words := splitWords(strings.ToLower(method))
+	previousParam := false
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" {
+			continue
+		}
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if previousParam {
+				words = append(words, "and")
+			} else {
+				words = append(words, "by")
+			}
+			words = append(words, splitWords(strings.Trim(segment, "{}"))...)
+			previousParam = true
+			continue
+		}
+		words = append(words, splitWords(segment)...)
+		previousParam = false
+	}
+	return joinWords(words, casing)
+}
+
+// Join combines identifiers into a single name in the given casing, e.g.
+// petstore_auth and token become petstoreAuthToken in camel case
+func Join(casing Casing, parts ...string) string {
+	var words []string
+	for _, part := range parts {
+		words = append(words, splitWords(part)...)
+	}
+	return joinWords(words, casing)
+}
+
+// splitWords splits an identifier on non alphanumeric characters and on
+// camelCase boundaries, returning lower-case words
+func splitWords(s string) []string {
+	var words []string
+	var current []rune
+	runes := []rune(s)
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, strings.ToLower(string(current)))
+			current = current[:0]
+		}
+	}
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		if unicode.IsUpper(r) && len(current) > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+	return words
+}
+
+// joinWords writes lower-case words in the given casing
+func joinWords(words []string, casing Casing) string {
+	if casing != CasingCamel {
+		return strings.Join(words, "_")
+	}
+	var b strings.Builder
+	for i, word := range words {
+		if i > 0 && word != "" {
+			word = strings.ToUpper(word[:1]) + word[1:]
+		}
+		b.WriteString(word)
+	}
+	return b.String()
+}
+
+// Registry hands out unique tool names. Names must be requested in a stable
+// order for the de-duplication to be deterministic.
+type Registry struct {
+	strategy Strategy
+	used     map[string]bool
+}
+
+// NewRegistry creates an empty registry for names built with strategy
+func NewRegistry(strategy Strategy) *Registry {
+	return &Registry{
+		strategy: strategy,
+		used:     make(map[string]bool),
+	}
+}
+
+// Unique returns name, or name suffixed with _2, _3, ... when it is taken
+func (r *Registry) Unique(name string) string {
+	unique := name
+	for i := 2; r.used[unique]; i++ {
+		suffix := fmt.Sprintf("_%d", i)
+		base := name
+		if r.strategy.MaxLength > 0 && len(base)+len(suffix) > r.strategy.MaxLength {
+			base = base[:r.strategy.MaxLength-len(suffix)]
+		}
+		unique = base + suffix
+	}
+	r.used[unique] = true
+	return unique
+}
@@ -0,0 +1,192 @@
+package parser
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// maxBundleSize limits the total uncompressed size of a specification bundle
+const maxBundleSize = 64 << 20
+
+// bundleEntryNames are the file names tried, in order, when no entry document
+// is given for a bundle
+var bundleEntryNames = []string{
+	"openapi.yaml", "openapi.yml", "openapi.json",
+	"swagger.yaml", "swagger.yml", "swagger.json",
+}
+
+// ParseBundle parses an OpenAPI specification packaged as a zip or tar
+// (optionally gzip-compressed) archive. entry is the path of the root document
+// inside the archive; when empty, the openapi.* or swagger.* file closest to
+// the archive root is used. Relative $ref references are resolved against the
+// other files of the archive and may not point outside of it.
+func (p *Parser) ParseBundle(archive []byte, entry string) error {
+	files, err := readBundle(archive)
+	if err != nil {
+		return fmt.Errorf("failed to read specification bundle: %w", err)
+	}
+
+	if entry == "" {
+		entry, err = findBundleEntry(files)
+		if err != nil {
+			return err
+		}
+	}
+	entry = bundlePath(entry)
+
+	content, ok := files[entry]
+	if !ok {
+		return fmt.Errorf("entry %q not found in specification bundle", strings.TrimPrefix(entry, "/"))
+	}
+
+	readRef := func(_ *openapi3.Loader, location *url.URL) ([]byte, error) {
+		if location.Host != "" || (location.Scheme != "" && location.Scheme != "file") {
+			return nil, fmt.Errorf("reference %q points outside the specification bundle", location.String())
+		}
+		data, ok := files[bundlePath(location.Path)]
+		if !ok {
+			return nil, fmt.Errorf("file %q not found in specification bundle", strings.TrimPrefix(location.Path, "/"))
+		}
+		return data, nil
+	}
+
+	return p.parse(content, &url.URL{Path: entry}, readRef)
+}
+
+// readBundle extracts every regular file of a zip, tar or tar.gz archive,
+// keyed by its slash-separated path rooted at "/"
+func readBundle(archive []byte) (map[string][]byte, error) {
+	switch {
+	case bytes.HasPrefix(archive, []byte("PK\x03\x04")):
+		return readZip(archive)
+	case bytes.HasPrefix(archive, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(bytes.NewReader(archive))
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		return readTar(gz)
+	case len(archive) > 262 && string(archive[257:262]) == "ustar":
+		return readTar(bytes.NewReader(archive))
+	default:
+		return nil, errors.New("unsupported archive format, expected zip, tar or tar.gz")
+	}
+}
+
+// readZip extracts the regular files of a zip archive
+func readZip(archive []byte) (map[string][]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]byte)
+	var total int64
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %q: %w", f.Name, err)
+		}
+		data, err := readLimited(rc, &total)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %q: %w", f.Name, err)
+		}
+		files[bundlePath(f.Name)] = data
+	}
+	return files, nil
+}
+
+// readTar extracts the regular files of a tar stream
+func readTar(r io.Reader) (map[string][]byte, error) {
+	tr := tar.NewReader(r)
+
+	files := make(map[string][]byte)
+	var total int64
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := readLimited(tr, &total)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %q: %w", header.Name, err)
+		}
+		files[bundlePath(header.Name)] = data
+	}
+	return files, nil
+}
+
+// readLimited reads r while keeping the running total below maxBundleSize
+func readLimited(r io.Reader, total *int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxBundleSize-*total+1))
+	if err != nil {
+		return nil, err
+	}
+	*total += int64(len(data))
+	if *total > maxBundleSize {
+		return nil, fmt.Errorf("bundle exceeds the maximum size of %d bytes", maxBundleSize)
+	}
+	return data, nil
+}
+
+// findBundleEntry picks the root document of a bundle: the shallowest file
+// whose name is one of bundleEntryNames
+func findBundleEntry(files map[string][]byte) (string, error) {
+	var candidates []string
+	for name := range files {
+		for _, entryName := range bundleEntryNames {
+			if path.Base(name) == entryName {
+				candidates = append(candidates, name)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("no entry document found in specification bundle, expected one of %s", strings.Join(bundleEntryNames, ", "))
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		di, dj := strings.Count(candidates[i], "/"), strings.Count(candidates[j], "/")
+		if di != dj {
+			return di < dj
+		}
+		return entryRank(candidates[i]) < entryRank(candidates[j])
+	})
+	return candidates[0], nil
+}
+
+// entryRank returns the position of the file name in bundleEntryNames
+func entryRank(name string) int {
+	for i, entryName := range bundleEntryNames {
+		if path.Base(name) == entryName {
+			return i
+		}
+	}
+	return len(bundleEntryNames)
+}
+
+// bundlePath normalizes an archive member name to a clean path rooted at "/",
+// so that "../" segments can never escape the bundle
+func bundlePath(name string) string {
+	return path.Clean("/" + strings.ReplaceAll(name, "\\", "/"))
+}
@@ -0,0 +1,610 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/invopop/yaml"
+)
+
+// normalizedOpenAPIVersion is the version 3.1 documents are rewritten to
+const normalizedOpenAPIVersion = "3.0.3"
+
+// isOpenAPI31 reports whether version declares an OpenAPI 3.1 document
+func isOpenAPI31(version string) bool {
+	return strings.HasPrefix(version, "3.1")
+}
+
+// normalizeOpenAPI31 rewrites an OpenAPI 3.1 document (JSON Schema 2020-12)
+// into the 3.0 shape understood by openapi3.T:
+//
+//   - type: [string, "null"] becomes type: string plus nullable: true, and
+//     anyOf/oneOf branches of type "null" are folded into nullable
+//   - examples arrays become example, const becomes a single value enum
+//   - numeric exclusiveMinimum/exclusiveMaximum become minimum/maximum flags
+//   - contentMediaType/contentEncoding become format: binary/byte
+//   - boolean schemas become empty (true) or never matching (false) schemas
+//   - $defs are hoisted into components.schemas and their $refs rewritten
+//   - components.pathItems references are inlined into paths
+//
+// webhooks describe calls made by the API to the consumer, which cannot be
+// turned into tools, so they are dropped once their schemas are processed.
+func normalizeOpenAPI31(data []byte) ([]byte, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI specification: %w", err)
+	}
+
+	n := &normalizer31{
+		doc:     doc,
+		defRefs: make(map[string]string),
+		used:    make(map[string]bool),
+	}
+	n.run()
+
+	return json.Marshal(doc)
+}
+
+// normalizeRefs31 wraps readRef so the files referenced by a 3.1 document
+// are normalized like the document itself before kin-openapi decodes them
+func normalizeRefs31(readRef openapi3.ReadFromURIFunc) openapi3.ReadFromURIFunc {
+	return func(loader *openapi3.Loader, location *url.URL) ([]byte, error) {
+		data, err := readRef(loader, location)
+		if err != nil {
+			return nil, err
+		}
+		if !isJSON(data) {
+			if data, err = yaml.YAMLToJSON(data); err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", location.Path, err)
+			}
+		}
+
+		var fragment interface{}
+		if err := json.Unmarshal(data, &fragment); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", location.Path, err)
+		}
+		return json.Marshal(normalizeFragment31(fragment))
+	}
+}
+
+// normalizeFragment31 normalizes the schemas of a referenced file. A file may
+// hold a single schema, a path item, a parameter or a whole set of
+// components, so every object looking like a schema or found under a schema
+// key is normalized; examples and defaults are data and are left alone.
+func normalizeFragment31(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if looksLikeSchema31(v) {
+			return walkSchema(v, "", normalizeSchema31)
+		}
+		for key, child := range v {
+			switch key {
+			case "schema":
+				v[key] = walkSchema(child, "", normalizeSchema31)
+			case "example", "examples", "default":
+			default:
+				v[key] = normalizeFragment31(child)
+			}
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = normalizeFragment31(child)
+		}
+	}
+	return value
+}
+
+// looksLikeSchema31 reports whether an object of a referenced file is a
+// schema, judging by its keywords
+func looksLikeSchema31(value map[string]interface{}) bool {
+	for _, keyword := range []string{"type", "properties", "items", "allOf", "anyOf", "oneOf", "enum", "const"} {
+		if _, ok := value[keyword]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizer31 holds the state of a single 3.1 to 3.0 rewrite
+type normalizer31 struct {
+	doc map[string]interface{}
+	// defRefs maps the JSON pointer of each $defs entry to its new
+	// #/components/schemas/... reference
+	defRefs map[string]string
+	// defOwners are the schemas whose $defs were hoisted
+	defOwners []map[string]interface{}
+	// used holds the component schema names already taken
+	used map[string]bool
+}
+
+// run performs the rewrite in place
+func (n *normalizer31) run() {
+	n.doc["openapi"] = normalizedOpenAPIVersion
+	delete(n.doc, "jsonSchemaDialect")
+	if _, ok := n.doc["paths"]; !ok {
+		n.doc["paths"] = map[string]interface{}{}
+	}
+	if info, ok := n.doc["info"].(map[string]interface{}); ok {
+		if license, ok := info["license"].(map[string]interface{}); ok {
+			delete(license, "identifier")
+		}
+	}
+
+	components, _ := n.doc["components"].(map[string]interface{})
+	if components == nil {
+		components = map[string]interface{}{}
+		n.doc["components"] = components
+	}
+	schemas, _ := components["schemas"].(map[string]interface{})
+	if schemas == nil {
+		schemas = map[string]interface{}{}
+		components["schemas"] = schemas
+	}
+	for name := range schemas {
+		n.used[name] = true
+	}
+
+	n.inlinePathItems(components)
+
+	// Hoist $defs first so the rewritten references are resolvable, then
+	// normalize every schema, including the hoisted ones. The schemas are
+	// visited in map order, so the owners of $defs are sorted by pointer to
+	// number colliding names the same way every time.
+	owners := map[string]interface{}{}
+	n.walkDocument(func(schema map[string]interface{}, pointer string) {
+		if _, ok := schema["$defs"].(map[string]interface{}); ok {
+			owners[pointer] = schema
+		}
+	})
+	hoisted := map[string]interface{}{}
+	for _, pointer := range sortedKeys(owners) {
+		n.collectDefs(owners[pointer].(map[string]interface{}), pointer, hoisted)
+	}
+	for _, owner := range n.defOwners {
+		delete(owner, "$defs")
+	}
+	for name, schema := range hoisted {
+		schemas[name] = schema
+	}
+	n.walkDocument(normalizeSchema31)
+	rewriteRefs(n.doc, n.defRefs)
+
+	delete(n.doc, "webhooks")
+}
+
+// inlinePathItems replaces path items that reference components.pathItems
+// with the referenced definition, since 3.0 has no such component
+func (n *normalizer31) inlinePathItems(components map[string]interface{}) {
+	pathItems, _ := components["pathItems"].(map[string]interface{})
+	delete(components, "pathItems")
+	if len(pathItems) == 0 {
+		return
+	}
+
+	paths, _ := n.doc["paths"].(map[string]interface{})
+	for path, item := range paths {
+		itemMap, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ref, _ := itemMap["$ref"].(string)
+		name := strings.TrimPrefix(ref, "#/components/pathItems/")
+		if name == ref {
+			continue
+		}
+		if target, ok := pathItems[unescapePointer(name)].(map[string]interface{}); ok {
+			paths[path] = deepCopyJSON(target)
+		}
+	}
+}
+
+// collectDefs records the $defs of schema for hoisting into components.schemas
+func (n *normalizer31) collectDefs(schema map[string]interface{}, pointer string, hoisted map[string]interface{}) {
+	defs, ok := schema["$defs"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	n.defOwners = append(n.defOwners, schema)
+
+	for _, defName := range sortedKeys(defs) {
+		name := defName
+		for i := 2; n.used[name]; i++ {
+			name = fmt.Sprintf("%s%d", defName, i)
+		}
+		n.used[name] = true
+		n.defRefs[pointer+"/$defs/"+escapePointer(defName)] = "#/components/schemas/" + escapePointer(name)
+		hoisted[name] = defs[defName]
+	}
+}
+
+// walkDocument calls visit for every schema of the document, parents first.
+// pointer is the JSON pointer of the schema, e.g. #/components/schemas/Pet.
+func (n *normalizer31) walkDocument(visit func(schema map[string]interface{}, pointer string)) {
+	if paths, ok := n.doc["paths"].(map[string]interface{}); ok {
+		for path, item := range paths {
+			walkPathItem(item, "#/paths/"+escapePointer(path), visit)
+		}
+	}
+	if webhooks, ok := n.doc["webhooks"].(map[string]interface{}); ok {
+		for name, item := range webhooks {
+			walkPathItem(item, "#/webhooks/"+escapePointer(name), visit)
+		}
+	}
+
+	components, ok := n.doc["components"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	if schemas, ok := components["schemas"].(map[string]interface{}); ok {
+		for name := range schemas {
+			schemas[name] = walkSchema(schemas[name], "#/components/schemas/"+escapePointer(name), visit)
+		}
+	}
+	for _, kind := range []string{"parameters", "headers"} {
+		if items, ok := components[kind].(map[string]interface{}); ok {
+			for name, item := range items {
+				walkParameter(item, "#/components/"+kind+"/"+escapePointer(name), visit)
+			}
+		}
+	}
+	if bodies, ok := components["requestBodies"].(map[string]interface{}); ok {
+		for name, body := range bodies {
+			walkContentHolder(body, "#/components/requestBodies/"+escapePointer(name), visit)
+		}
+	}
+	if responses, ok := components["responses"].(map[string]interface{}); ok {
+		for name, response := range responses {
+			walkResponse(response, "#/components/responses/"+escapePointer(name), visit)
+		}
+	}
+	if callbacks, ok := components["callbacks"].(map[string]interface{}); ok {
+		for name, callback := range callbacks {
+			walkCallback(callback, "#/components/callbacks/"+escapePointer(name), visit)
+		}
+	}
+}
+
+// walkPathItem visits the schemas of a path item and its operations
+func walkPathItem(item interface{}, pointer string, visit func(map[string]interface{}, string)) {
+	itemMap, ok := item.(map[string]interface{})
+	if !ok {
+		return
+	}
+	walkParameters(itemMap["parameters"], pointer+"/parameters", visit)
+
+	for _, method := range []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"} {
+		operation, ok := itemMap[method].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		opPointer := pointer + "/" + method
+		walkParameters(operation["parameters"], opPointer+"/parameters", visit)
+		walkContentHolder(operation["requestBody"], opPointer+"/requestBody", visit)
+		if responses, ok := operation["responses"].(map[string]interface{}); ok {
+			for code, response := range responses {
+				walkResponse(response, opPointer+"/responses/"+escapePointer(code), visit)
+			}
+		}
+		if callbacks, ok := operation["callbacks"].(map[string]interface{}); ok {
+			for name, callback := range callbacks {
+				walkCallback(callback, opPointer+"/callbacks/"+escapePointer(name), visit)
+			}
+		}
+	}
+}
+
+// walkCallback visits the schemas of the path items of a callback
+func walkCallback(callback interface{}, pointer string, visit func(map[string]interface{}, string)) {
+	callbackMap, ok := callback.(map[string]interface{})
+	if !ok {
+		return
+	}
+	for expression, item := range callbackMap {
+		walkPathItem(item, pointer+"/"+escapePointer(expression), visit)
+	}
+}
+
+// walkParameters visits the schemas of a parameter list
+func walkParameters(parameters interface{}, pointer string, visit func(map[string]interface{}, string)) {
+	list, ok := parameters.([]interface{})
+	if !ok {
+		return
+	}
+	for i, parameter := range list {
+		walkParameter(parameter, fmt.Sprintf("%s/%d", pointer, i), visit)
+	}
+}
+
+// walkParameter visits the schemas of a parameter or header object
+func walkParameter(parameter interface{}, pointer string, visit func(map[string]interface{}, string)) {
+	parameterMap, ok := parameter.(map[string]interface{})
+	if !ok {
+		return
+	}
+	if schema, ok := parameterMap["schema"]; ok {
+		parameterMap["schema"] = walkSchema(schema, pointer+"/schema", visit)
+	}
+	walkContentHolder(parameterMap, pointer, visit)
+}
+
+// walkResponse visits the schemas of a response's content and headers
+func walkResponse(response interface{}, pointer string, visit func(map[string]interface{}, string)) {
+	responseMap, ok := response.(map[string]interface{})
+	if !ok {
+		return
+	}
+	walkContentHolder(responseMap, pointer, visit)
+	if headers, ok := responseMap["headers"].(map[string]interface{}); ok {
+		for name, header := range headers {
+			walkParameter(header, pointer+"/headers/"+escapePointer(name), visit)
+		}
+	}
+}
+
+// walkContentHolder visits the media type schemas of an object with content
+func walkContentHolder(holder interface{}, pointer string, visit func(map[string]interface{}, string)) {
+	holderMap, ok := holder.(map[string]interface{})
+	if !ok {
+		return
+	}
+	content, ok := holderMap["content"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	for mediaType, media := range content {
+		mediaMap, ok := media.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if schema, ok := mediaMap["schema"]; ok {
+			mediaMap["schema"] = walkSchema(schema, pointer+"/content/"+escapePointer(mediaType)+"/schema", visit)
+		}
+	}
+}
+
+// walkSchema visits schema and every schema nested in it, parents first, and
+// returns the schema to store back, which differs from the input only for
+// boolean schemas
+func walkSchema(schema interface{}, pointer string, visit func(map[string]interface{}, string)) interface{} {
+	if b, ok := schema.(bool); ok {
+		if b {
+			return map[string]interface{}{}
+		}
+		return map[string]interface{}{"not": map[string]interface{}{}}
+	}
+	schemaMap, ok := schema.(map[string]interface{})
+	if !ok {
+		return schema
+	}
+
+	visit(schemaMap, pointer)
+
+	for _, key := range []string{"items", "not", "contains", "if", "then", "else", "propertyNames"} {
+		if child, ok := schemaMap[key]; ok {
+			schemaMap[key] = walkSchema(child, pointer+"/"+key, visit)
+		}
+	}
+	// additionalProperties may stay a boolean in 3.0
+	if child, ok := schemaMap["additionalProperties"].(map[string]interface{}); ok {
+		walkSchema(child, pointer+"/additionalProperties", visit)
+	}
+	for _, key := range []string{"properties", "patternProperties", "$defs", "dependentSchemas"} {
+		if children, ok := schemaMap[key].(map[string]interface{}); ok {
+			for name, child := range children {
+				children[name] = walkSchema(child, pointer+"/"+key+"/"+escapePointer(name), visit)
+			}
+		}
+	}
+	for _, key := range []string{"allOf", "anyOf", "oneOf", "prefixItems"} {
+		if children, ok := schemaMap[key].([]interface{}); ok {
+			for i, child := range children {
+				children[i] = walkSchema(child, fmt.Sprintf("%s/%s/%d", pointer, key, i), visit)
+			}
+		}
+	}
+	return schemaMap
+}
+
+// normalizeSchema31 rewrites the 3.1 keywords of a single schema
+func normalizeSchema31(schema map[string]interface{}, _ string) {
+	switch typ := schema["type"].(type) {
+	case string:
+		if typ == "null" {
+			delete(schema, "type")
+			schema["nullable"] = true
+		}
+	case []interface{}:
+		var types []interface{}
+		for _, t := range typ {
+			if t == "null" {
+				schema["nullable"] = true
+				continue
+			}
+			types = append(types, t)
+		}
+		delete(schema, "type")
+		switch len(types) {
+		case 0:
+		case 1:
+			schema["type"] = types[0]
+		default:
+			// Several non-null types can only be expressed as alternatives
+			alternatives := make([]interface{}, 0, len(types))
+			for _, t := range types {
+				alternatives = append(alternatives, map[string]interface{}{"type": t})
+			}
+			if _, exists := schema["anyOf"]; exists {
+				allOf, _ := schema["allOf"].([]interface{})
+				schema["allOf"] = append(allOf, map[string]interface{}{"anyOf": alternatives})
+			} else {
+				schema["anyOf"] = alternatives
+			}
+		}
+	}
+
+	// anyOf: [X, {type: "null"}] is the 3.1 spelling of a nullable X
+	for _, key := range []string{"anyOf", "oneOf"} {
+		alternatives, ok := schema[key].([]interface{})
+		if !ok {
+			continue
+		}
+		kept := alternatives[:0]
+		for _, alternative := range alternatives {
+			if alternativeMap, ok := alternative.(map[string]interface{}); ok && len(alternativeMap) == 1 && alternativeMap["type"] == "null" {
+				schema["nullable"] = true
+				continue
+			}
+			kept = append(kept, alternative)
+		}
+		switch {
+		case len(kept) == 0:
+			delete(schema, key)
+		case len(kept) == 1 && len(kept) != len(alternatives):
+			// A single remaining alternative is simply the schema itself
+			delete(schema, key)
+			allOf, _ := schema["allOf"].([]interface{})
+			schema["allOf"] = append(allOf, kept[0])
+		default:
+			schema[key] = kept
+		}
+	}
+
+	if examples, ok := schema["examples"].([]interface{}); ok {
+		if _, exists := schema["example"]; !exists && len(examples) > 0 {
+			schema["example"] = examples[0]
+		}
+		delete(schema, "examples")
+	}
+
+	if value, ok := schema["const"]; ok {
+		if _, exists := schema["enum"]; !exists {
+			schema["enum"] = []interface{}{value}
+		}
+		if _, exists := schema["type"]; !exists {
+			if typ := jsonType(value); typ != "" {
+				schema["type"] = typ
+			}
+		}
+		delete(schema, "const")
+	}
+
+	for keyword, bound := range map[string]string{"exclusiveMinimum": "minimum", "exclusiveMaximum": "maximum"} {
+		if value, ok := schema[keyword].(float64); ok {
+			schema[bound] = value
+			schema[keyword] = true
+		}
+	}
+
+	if _, ok := schema["format"]; !ok {
+		if encoding, _ := schema["contentEncoding"].(string); encoding == "base64" {
+			schema["format"] = "byte"
+		} else if _, ok := schema["contentMediaType"]; ok {
+			schema["format"] = "binary"
+		}
+	}
+
+	for _, keyword := range []string{"$schema", "$id", "$anchor", "$comment", "contentEncoding", "contentMediaType"} {
+		delete(schema, keyword)
+	}
+}
+
+// jsonType returns the JSON Schema type of a decoded JSON value
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return ""
+	}
+}
+
+// rewriteRefs replaces every $ref in value that points into a hoisted $defs
+// entry with its new components.schemas reference
+func rewriteRefs(value interface{}, defRefs map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if ref, ok := v["$ref"].(string); ok {
+			v["$ref"] = rewriteRef(ref, defRefs)
+		}
+		for _, child := range v {
+			rewriteRefs(child, defRefs)
+		}
+	case []interface{}:
+		for _, child := range v {
+			rewriteRefs(child, defRefs)
+		}
+	}
+}
+
+// rewriteRef maps a single reference, keeping any pointer suffix below the
+// hoisted definition. The innermost definition containing the reference wins.
+func rewriteRef(ref string, defRefs map[string]string) string {
+	if target, ok := defRefs[ref]; ok {
+		return target
+	}
+	longest := ""
+	for pointer := range defRefs {
+		if strings.HasPrefix(ref, pointer+"/") && len(pointer) > len(longest) {
+			longest = pointer
+		}
+	}
+	if longest == "" {
+		return ref
+	}
+	return defRefs[longest] + strings.TrimPrefix(ref, longest)
+}
+
+// escapePointer escapes a JSON pointer reference token
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+// unescapePointer reverses escapePointer
+func unescapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+}
+
+// deepCopyJSON copies a value decoded from JSON
+func deepCopyJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			copied[key] = deepCopyJSON(child)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, child := range v {
+			copied[i] = deepCopyJSON(child)
+		}
+		return copied
+	default:
+		return v
+	}
+}
+
+// sortedKeys returns the keys of m in ascending order
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -3,13 +3,20 @@ package parser
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/getkin/kin-openapi/openapi3"
-	"gopkg.in/yaml.v3"
+	"github.com/invopop/yaml"
 )
 
+// ErrValidation is wrapped by the errors of documents failing validation
+var ErrValidation = errors.New("OpenAPI specification validation failed")
+
 // Parser represents an OpenAPI parser
 type Parser struct {
 	document *openapi3.T
@@ -28,7 +35,9 @@ func (p *Parser) SetValidation(validate bool) {
 	p.validate = validate
 }
 
-// ParseFile parses an OpenAPI specification file
+// ParseFile parses an OpenAPI specification file. Relative $ref references to
+// other local files (e.g. schemas/pet.yaml) are resolved against the directory
+// of the file.
 func (p *Parser) ParseFile(path string) error {
 	// Read the file
 	data, err := os.ReadFile(path)
@@ -36,38 +45,126 @@ func (p *Parser) ParseFile(path string) error {
 		return fmt.Errorf("failed to read file: %w", err)
 	}
 
-	return p.ParseContent(data)
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve file path: %w", err)
+	}
+
+	return p.parse(data, &url.URL{Path: filepath.ToSlash(absPath)}, openapi3.ReadFromFile)
 }
 
-// ParseContent parses OpenAPI specification from content
+// ParseContent parses OpenAPI specification from content. Since the content
+// has no location, only internal references (#/components/...) are resolved.
 func (p *Parser) ParseContent(content []byte) error {
-	var doc openapi3.T
+	return p.parse(content, nil, nil)
+}
 
-	// 根据内容格式选择解析方式
-	if isJSON(content) {
-		// JSON 格式
-		if err := json.Unmarshal(content, &doc); err != nil {
+// parse unmarshals the specification, resolves its $ref references and
+// optionally validates it. location is the URI of the document and is used
+// as the base for relative references; it may be nil for in-memory content.
+// readRef loads externally referenced documents; when nil, external
+// references are rejected.
+func (p *Parser) parse(content []byte, location *url.URL, readRef openapi3.ReadFromURIFunc) error {
+	// 根据内容格式选择解析方式，YAML 先转换为 JSON，
+	// 以便复用 openapi3 类型上的 UnmarshalJSON（扩展字段、引用等）
+	data := content
+	if !isJSON(content) {
+		converted, err := yaml.YAMLToJSON(content)
+		if err != nil {
 			return fmt.Errorf("failed to parse OpenAPI specification: %w", err)
 		}
-	} else {
-		// YAML 格式
-		if err := yaml.Unmarshal(content, &doc); err != nil {
+		data = converted
+	}
+
+	doc := &openapi3.T{}
+	switch version := detectVersion(data); {
+	case version.Swagger == "2.0":
+		// Swagger 2.0 documents are upgraded to OpenAPI 3, then their references are resolved
+		upgraded, err := upgradeSwagger2(data, location, readRef)
+		if err != nil {
+			return err
+		}
+		doc = upgraded
+	case version.Swagger != "":
+		return fmt.Errorf("unsupported Swagger version %q, only 2.0 is supported", version.Swagger)
+	default:
+		// OpenAPI 3.1 uses JSON Schema 2020-12 constructs openapi3.T cannot hold
+		if isOpenAPI31(version.OpenAPI) {
+			normalized, err := normalizeOpenAPI31(data)
+			if err != nil {
+				return err
+			}
+			data = normalized
+			if readRef != nil {
+				readRef = normalizeRefs31(readRef)
+			}
+		}
+
+		if err := json.Unmarshal(data, doc); err != nil {
 			return fmt.Errorf("failed to parse OpenAPI specification: %w", err)
 		}
+
+		// Resolve $ref references so the converter sees fully populated values
+		if err := resolveRefs(doc, location, readRef); err != nil {
+			return err
+		}
 	}
 
 	// Validate the document if requested
 	if p.validate {
 		ctx := context.Background()
 		if err := doc.Validate(ctx); err != nil {
-			return fmt.Errorf("OpenAPI specification validation failed: %w", err)
+			return fmt.Errorf("%w: %w", ErrValidation, err)
 		}
 	}
 
-	p.document = &doc
+	p.document = doc
+	return nil
+}
+
+// resolveRefs resolves every $ref in the document in place. Recursive schemas
+// (e.g. a tree node whose children refer back to the node) are resolved into
+// pointer cycles; reference chains that never reach a concrete value, such as
+// A -> B -> A, are reported as errors.
+func resolveRefs(doc *openapi3.T, location *url.URL, readRef openapi3.ReadFromURIFunc) error {
+	loader := openapi3.NewLoader()
+	if readRef != nil {
+		loader.IsExternalRefsAllowed = true
+		loader.ReadFromURIFunc = readRef
+	}
+	if err := loader.ResolveRefsIn(doc, location); err != nil {
+		if refErr := circularRefError(err); refErr != nil {
+			return refErr
+		}
+		return fmt.Errorf("failed to resolve references: %w", err)
+	}
 	return nil
 }
 
+// circularRefError rewrites the loader's circular reference error into a
+// readable one, or returns nil if err is not about a reference cycle
+func circularRefError(err error) error {
+	if !strings.HasPrefix(err.Error(), openapi3.CircularReferenceError) {
+		return nil
+	}
+	chain := strings.TrimPrefix(strings.TrimPrefix(err.Error(), openapi3.CircularReferenceError), " - ")
+	return fmt.Errorf("failed to resolve references: circular $ref detected: %s", shortestCycle(chain))
+}
+
+// shortestCycle cuts the loader's chain, which follows the cycle until its
+// depth limit, after the first repeated ref, e.g. A -> B -> A
+func shortestCycle(chain string) string {
+	refs := strings.Split(chain, " -> ")
+	seen := make(map[string]bool, len(refs))
+	for i, ref := range refs {
+		if seen[ref] {
+			return strings.Join(refs[:i+1], " -> ")
+		}
+		seen[ref] = true
+	}
+	return chain
+}
+
 // GetDocument returns the parsed OpenAPI document
 func (p *Parser) GetDocument() *openapi3.T {
 	return p.document
@@ -102,13 +199,3 @@ func isJSON(data []byte) bool {
 	var js json.RawMessage
 	return json.Unmarshal(data, &js) == nil
 }
-
-// GetOperationID returns the operation ID for a given path and method
-func (p *Parser) GetOperationID(path, method string, operation *openapi3.Operation) string {
-	if operation.OperationID != "" {
-		return operation.OperationID
-	}
-
-	// Generate a default operation ID based on the path and method
-	return fmt.Sprintf("%s_%s", method, path)
-}
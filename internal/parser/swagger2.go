@@ -0,0 +1,196 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi2conv"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// specVersion holds the version fields used to tell specification formats apart
+type specVersion struct {
+	OpenAPI string `json:"openapi"`
+	Swagger string `json:"swagger"`
+}
+
+// detectVersion reads the openapi/swagger version fields of a JSON document
+func detectVersion(data []byte) specVersion {
+	var version specVersion
+	_ = json.Unmarshal(data, &version)
+	return version
+}
+
+// upgradeSwagger2 converts a Swagger 2.0 document to OpenAPI 3 so the rest of
+// the pipeline only deals with a single model. body/formData parameters become
+// request bodies, definitions become component schemas, host/basePath/schemes
+// become servers, and produces/consumes become media types. location and
+// readRef resolve relative $ref references to other files, as for OpenAPI 3
+// documents.
+func upgradeSwagger2(data []byte, location *url.URL, readRef openapi3.ReadFromURIFunc) (*openapi3.T, error) {
+	var doc2 openapi2.T
+	if err := json.Unmarshal(data, &doc2); err != nil {
+		return nil, fmt.Errorf("failed to parse Swagger 2.0 specification: %w", err)
+	}
+
+	applyDefaultMediaTypes(&doc2)
+
+	doc, err := toV3(&doc2)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert Swagger 2.0 specification to OpenAPI 3: %w", err)
+	}
+	if err := resolveRefs(doc, location, readRef); err != nil {
+		return nil, err
+	}
+
+	// Without a host the API is served relative to the document, so keep the
+	// basePath as a relative server URL instead of dropping it
+	if doc2.Host == "" && doc2.BasePath != "" && doc2.BasePath != "/" {
+		doc.AddServer(&openapi3.Server{URL: doc2.BasePath})
+	}
+
+	return doc, nil
+}
+
+// toV3 converts a Swagger 2.0 document like openapi2conv.ToV3 but leaves its
+// references unresolved: ToV3 resolves them with a loader of its own, which
+// rejects references to other files
+func toV3(doc2 *openapi2.T) (*openapi3.T, error) {
+	doc3 := &openapi3.T{
+		OpenAPI:      "3.0.3",
+		Info:         &doc2.Info,
+		Components:   &openapi3.Components{},
+		Tags:         doc2.Tags,
+		Extensions:   extensionsOnly(doc2.Extensions),
+		ExternalDocs: doc2.ExternalDocs,
+	}
+
+	if host := doc2.Host; host != "" {
+		if strings.Contains(host, "/") {
+			return nil, fmt.Errorf("invalid host %q, it must not include the scheme or a path", host)
+		}
+		schemes := doc2.Schemes
+		if len(schemes) == 0 {
+			schemes = []string{"https"}
+		}
+		basePath := doc2.BasePath
+		if basePath == "" {
+			basePath = "/"
+		}
+		for _, scheme := range schemes {
+			serverURL := url.URL{Scheme: scheme, Host: host, Path: basePath}
+			doc3.AddServer(&openapi3.Server{URL: serverURL.String()})
+		}
+	}
+
+	doc3.Components.Schemas = make(openapi3.Schemas)
+	if len(doc2.Parameters) != 0 {
+		doc3.Components.Parameters = make(openapi3.ParametersMap)
+		doc3.Components.RequestBodies = make(openapi3.RequestBodies)
+		for name, parameter := range doc2.Parameters {
+			v3Parameter, v3RequestBody, v3Schemas, err := openapi2conv.ToV3Parameter(doc3.Components, parameter, doc2.Consumes)
+			switch {
+			case err != nil:
+				return nil, err
+			case v3RequestBody != nil:
+				doc3.Components.RequestBodies[name] = v3RequestBody
+			case v3Schemas != nil:
+				for _, v3Schema := range v3Schemas {
+					doc3.Components.Schemas[name] = v3Schema
+				}
+			default:
+				doc3.Components.Parameters[name] = v3Parameter
+			}
+		}
+	}
+
+	if len(doc2.Paths) != 0 {
+		doc3.Paths = make(openapi3.Paths, len(doc2.Paths))
+		for path, pathItem := range doc2.Paths {
+			v3PathItem, err := openapi2conv.ToV3PathItem(doc2, doc3.Components, pathItem, doc2.Consumes)
+			if err != nil {
+				return nil, err
+			}
+			doc3.Paths[path] = v3PathItem
+		}
+	}
+
+	if len(doc2.Responses) != 0 {
+		doc3.Components.Responses = make(openapi3.Responses, len(doc2.Responses))
+		for name, response := range doc2.Responses {
+			v3Response, err := openapi2conv.ToV3Response(response, doc2.Produces)
+			if err != nil {
+				return nil, err
+			}
+			doc3.Components.Responses[name] = v3Response
+		}
+	}
+
+	for name, schema := range openapi2conv.ToV3Schemas(doc2.Definitions) {
+		doc3.Components.Schemas[name] = schema
+	}
+
+	if len(doc2.SecurityDefinitions) != 0 {
+		doc3.Components.SecuritySchemes = make(openapi3.SecuritySchemes, len(doc2.SecurityDefinitions))
+		for name, securityScheme := range doc2.SecurityDefinitions {
+			v3SecurityScheme, err := openapi2conv.ToV3SecurityScheme(securityScheme)
+			if err != nil {
+				return nil, err
+			}
+			doc3.Components.SecuritySchemes[name] = v3SecurityScheme
+		}
+	}
+
+	doc3.Security = openapi2conv.ToV3SecurityRequirements(doc2.Security)
+	return doc3, nil
+}
+
+// extensionsOnly drops the fields of a Swagger 2.0 document that are not x-
+// extensions, which openapi2.T keeps with them
+func extensionsOnly(extensions map[string]interface{}) map[string]interface{} {
+	for name := range extensions {
+		if !strings.HasPrefix(name, "x-") {
+			delete(extensions, name)
+		}
+	}
+	return extensions
+}
+
+// applyDefaultMediaTypes fills in the produces/consumes lists of each operation
+// so the converted request bodies and responses always carry a media type.
+// Operations inherit the document level lists; when neither is declared,
+// JSON is assumed, and form parameters default to a form media type.
+func applyDefaultMediaTypes(doc2 *openapi2.T) {
+	for _, pathItem := range doc2.Paths {
+		if pathItem == nil {
+			continue
+		}
+		for _, operation := range pathItem.Operations() {
+			if len(operation.Produces) == 0 {
+				operation.Produces = doc2.Produces
+			}
+			if len(operation.Consumes) == 0 && len(doc2.Consumes) == 0 {
+				operation.Consumes = []string{defaultConsumes(operation.Parameters)}
+			}
+		}
+	}
+}
+
+// defaultConsumes picks the request media type implied by the parameters of
+// an operation that does not declare one
+func defaultConsumes(parameters openapi2.Parameters) string {
+	mediaType := "application/json"
+	for _, parameter := range parameters {
+		if parameter == nil || parameter.In != "formData" {
+			continue
+		}
+		if parameter.Type == "file" {
+			return "multipart/form-data"
+		}
+		mediaType = "application/x-www-form-urlencoded"
+	}
+	return mediaType
+}